@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/storage"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteMyAccount deletes the account of the caller after checking the
+// password. The account is deactivated right away and can be restored by
+// logging in with the password until the grace period is over, then its
+// content is purged.
+func (h *Handler) DeleteMyAccount(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+
+	accountKey := loginAccountKey(userInfo.User_id)
+	if h.rejectLockedLogin(c, accountKey) {
+		return
+	}
+
+	hash, err := h.storage.User().GetPasswordHash(c.Request.Context(), userInfo.User_id)
+	if err != nil {
+		h.log.Error("error get password:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	err = helper.ComparePasswords([]byte(hash), []byte(req.Password))
+	if err != nil {
+		if errors.Is(err, helper.ErrPasswordMismatch) {
+			h.registerLoginFailure(c, accountKey, config.LoginMaxAccountFailures)
+			h.recordAuthEvent(c, models.CreateAuthEvent{
+				UserId:    userInfo.User_id,
+				EventType: models.AuthEventAccountDelete,
+				Result:    models.AuthResultFailure,
+				Detail:    "invalid_password",
+			})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "password didn't match"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "password comparison failed"})
+		}
+		return
+	}
+
+	purgeAfter := time.Now().Add(h.cfg.AccountDeletionGracePeriod)
+	err = h.storage.User().ScheduleDeletion(c.Request.Context(), &models.ScheduleDeletion{
+		UserId:     userInfo.User_id,
+		PurgeAfter: purgeAfter,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error deleting account:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	_, err = h.storage.Session().RevokeAllSessions(c.Request.Context(), &models.RevokeAllSessions{UserId: userInfo.User_id})
+	if err != nil {
+		h.log.Error("error revoking sessions:", logger.Error(err))
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userInfo.User_id,
+		EventType: models.AuthEventAccountDelete,
+		Result:    models.AuthResultSuccess,
+		Detail:    purgeAfter.Format(time.RFC3339),
+	})
+
+	h.clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "account deleted, log in before purge_after to restore it",
+		"purge_after": purgeAfter.Format(time.RFC3339),
+	})
+}
+
+// RestoreUser reactivates a deleted account for admins, as long as it wasn't purged
+func (h *Handler) RestoreUser(c *gin.Context) {
+	adminInfo := c.MustGet("user_info").(helper.TokenInfo)
+	id := c.Param("id")
+
+	err := h.storage.User().RestoreUser(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no deleted account to restore"})
+			return
+		}
+		h.log.Error("error restoring user:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    id,
+		ActorId:   adminInfo.User_id,
+		EventType: models.AuthEventAccountRestore,
+		Result:    models.AuthResultSuccess,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "restored user id": id})
+}
+
+// restoreAccount reactivates an account deleted by its owner who logged in
+// again during the grace period
+func (h *Handler) restoreAccount(c *gin.Context, userId string) error {
+	if err := h.storage.User().RestoreUser(c.Request.Context(), userId); err != nil {
+		return err
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userId,
+		EventType: models.AuthEventAccountRestore,
+		Result:    models.AuthResultSuccess,
+		Detail:    "login",
+	})
+	return nil
+}
+
+// purgeDeletedAccounts purges the accounts whose deletion grace period is over
+// and removes their uploaded files and export archives
+func (h *Handler) purgeDeletedAccounts(ctx context.Context) {
+	ids, err := h.storage.User().GetUsersToPurge(ctx, config.AccountPurgeBatchSize)
+	if err != nil {
+		h.log.Error("error get users to purge:", logger.Error(err))
+		return
+	}
+
+	for _, id := range ids {
+		purged, err := h.storage.User().PurgeUser(ctx, id)
+		if err != nil {
+			if !errors.Is(err, storage.ErrUserNotFound) {
+				h.log.Error("error purging user:", logger.Error(err), logger.String("user_id", id))
+			}
+			continue
+		}
+
+		for _, link := range purged.Media {
+			if !strings.HasPrefix(link, "/media/") || strings.Contains(link, "..") {
+				continue
+			}
+			if err := h.files.Delete(ctx, link); err != nil {
+				h.log.Error("error deleting media:", logger.String("error", err.Message), logger.String("media", link))
+			}
+		}
+		for _, path := range purged.ExportFiles {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				h.log.Error("error removing export:", logger.Error(err), logger.String("path", path))
+			}
+		}
+
+		h.log.Info("purged deleted account", logger.String("user_id", id))
+	}
+}
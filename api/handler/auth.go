@@ -5,12 +5,16 @@ import (
 	"auth/models"
 	"auth/pkg/helper"
 	"auth/pkg/logger"
+	"auth/pkg/validation"
+	"auth/storage"
 	"context"
-	"fmt"
+	"errors"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func (h *Handler) SignUp(c *gin.Context) {
@@ -23,7 +27,19 @@ func (h *Handler) SignUp(c *gin.Context) {
 		return
 	}
 
-	hashedPass, err := helper.GeneratePasswordHash(user.Password)
+	user.Username = strings.TrimSpace(user.Username)
+	user.Email = strings.TrimSpace(user.Email)
+
+	var errs validation.Errors
+	h.validate.Username(&errs, "username", user.Username)
+	h.validate.Email(&errs, "email", user.Email)
+	h.validate.Password(&errs, "password", user.Password, user.Username)
+	if !errs.Empty() {
+		validationFailed(c, http.StatusBadRequest, errs)
+		return
+	}
+
+	hashedPass, err := helper.GeneratePasswordHash(h.cfg, user.Password)
 	if err != nil {
 		h.log.Error("error while generating hash password:", logger.Error(err))
 		c.JSON(http.StatusBadRequest, "invalid body")
@@ -33,11 +49,25 @@ func (h *Handler) SignUp(c *gin.Context) {
 
 	resp, err := h.storage.User().CreateUser(c.Request.Context(), &user)
 	if err != nil {
-		fmt.Println("error User Create:", err.Error())
-		c.JSON(http.StatusInternalServerError, "username is already used, enter another one")
+		if userConflict(c, err) {
+			return
+		}
+		h.log.Error("error User Create:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, "internal server error")
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"message": "created", "id": resp})
+
+	if err = h.sendEmailVerification(c, resp, user.Username, user.Email); err != nil {
+		h.log.Error("error sending verification mail:", logger.Error(err))
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    resp,
+		EventType: models.AuthEventSignUp,
+		Result:    models.AuthResultSuccess,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"message": "created, check your email to verify the account", "id": resp})
 }
 
 func (h *Handler) Login(c *gin.Context) {
@@ -49,30 +79,280 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
+	ipKey := loginIPKey(c.ClientIP())
+	if h.rejectLockedLogin(c, ipKey) {
+		h.recordLoginFailure(c, "", "ip_locked")
+		return
+	}
+
 	resp, err := h.storage.User().GetByUsername(context.Background(), &models.LoginRequest{
 		Username: req.Username,
 	})
-	if err != nil {
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
 		h.log.Error("error get by username:", logger.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "not found username"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	// unknown usernames are counted and locked like existing ones, so the
+	// lock doesn't tell which accounts exist
+	accountKey := loginNameKey(req.Username)
+	if h.rejectLockedLogin(c, accountKey) {
+		userId := ""
+		if resp != nil {
+			userId = resp.User_id
+		}
+		h.recordLoginFailure(c, userId, "account_locked")
+		return
+	}
+
+	if resp == nil {
+		// keep the response time of unknown usernames close to wrong passwords
+		_ = helper.ComparePasswords(h.dummyPasswordHash, []byte(req.Password))
+		h.registerLoginFailure(c, ipKey, config.LoginMaxIPFailures)
+		h.registerLoginFailure(c, accountKey, config.LoginMaxAccountFailures)
+		h.recordLoginFailure(c, "", "unknown_user")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "login or password didn't match"})
 		return
 	}
 
 	// Compare hashed password with plain text password
 	err = helper.ComparePasswords([]byte(resp.Password), []byte(req.Password))
 	if err != nil {
-		if err == bcrypt.ErrMismatchedHashAndPassword {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "login or password didn't match"})
+		if errors.Is(err, helper.ErrPasswordMismatch) {
+			h.registerLoginFailure(c, ipKey, config.LoginMaxIPFailures)
+			h.registerLoginFailure(c, accountKey, config.LoginMaxAccountFailures)
+			h.recordLoginFailure(c, resp.User_id, "invalid_password")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "login or password didn't match"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "password comparison failed"})
 		}
 		return
 	}
 
+	if err = h.storage.LoginFailure().ResetLoginFailures(c.Request.Context(), accountKey); err != nil {
+		h.log.Error("error resetting login failures:", logger.Error(err))
+	}
+
+	h.upgradePasswordHash(c, resp.User_id, resp.Password, req.Password)
+
+	h.completeLogin(c, resp, models.LoginMethodPassword)
+}
+
+// completeLogin answers a successful first factor: with a two factor challenge
+// when the user has it enabled, otherwise with a new session's tokens. The
+// login is recorded with the method once the tokens are issued, and an
+// account in its deletion grace period is restored only then.
+func (h *Handler) completeLogin(c *gin.Context, user *models.LoginDataRespond, method string) {
+	if user.TwoFactorEnabled {
+		challenge, err := h.mfaChallenge(user.User_id)
+		if err != nil {
+			h.log.Error("error generating mfa token:", logger.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	if user.DeletionScheduled {
+		if err := h.restoreAccount(c, user.User_id); err != nil {
+			h.log.Error("error restoring account:", logger.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+	}
+
+	tokens, err := h.issueTokens(c, helper.TokenInfo{
+		User_id:       user.User_id,
+		Username:      user.Username,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		PhoneVerified: user.PhoneVerified,
+	})
+	if err != nil {
+		h.log.Error("error issuing tokens:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    user.User_id,
+		EventType: models.AuthEventLogin,
+		Result:    models.AuthResultSuccess,
+		Detail:    method,
+	})
+	h.respondTokens(c, tokens)
+}
+
+// recordLoginFailure records a rejected login. userId is empty when the account is unknown.
+func (h *Handler) recordLoginFailure(c *gin.Context, userId, reason string) {
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userId,
+		EventType: models.AuthEventLogin,
+		Result:    models.AuthResultFailure,
+		Detail:    reason,
+	})
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token pair.
+// Presenting a refresh token that was already rotated revokes its whole family.
+func (h *Handler) Refresh(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+
+	if req.RefreshToken == "" && h.cookieAuth() {
+		req.RefreshToken, _ = c.Cookie(refreshTokenCookie)
+		if req.RefreshToken != "" && !validCSRF(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "CSRF token is missing or doesn't match"})
+			return
+		}
+	}
+
+	if req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh token is required"})
+		return
+	}
+
+	token, err := h.storage.RefreshToken().GetByHash(c.Request.Context(), helper.HashToken(req.RefreshToken))
+	if err != nil {
+		h.log.Error("error get refresh token:", logger.Error(err))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		return
+	}
+
+	if token.Used && !token.Revoked {
+		h.log.Warn("refresh token reuse detected", logger.String("family_id", token.FamilyId))
+		h.revokeSession(c, token.UserId, token.FamilyId)
+		h.recordAuthEvent(c, models.CreateAuthEvent{
+			UserId:    token.UserId,
+			EventType: models.AuthEventTokenReuse,
+			Result:    models.AuthResultFailure,
+			Detail:    "session revoked",
+		})
+	}
+
+	if token.Used || token.Revoked || time.Now().After(token.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		return
+	}
+
+	refreshToken, err := helper.GenerateOpaqueToken(32)
+	if err != nil {
+		h.log.Error("error generating refresh token:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	_, err = h.storage.RefreshToken().RotateRefreshToken(c.Request.Context(), token.ID, &models.CreateRefreshToken{
+		FamilyId:  token.FamilyId,
+		UserId:    token.UserId,
+		TokenHash: helper.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(config.RefreshTokenExpireTime),
+	})
+	if err != nil {
+		h.log.Warn("refresh token rotation failed, revoking family:", logger.Error(err))
+		h.revokeSession(c, token.UserId, token.FamilyId)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		return
+	}
+
+	accessToken, err := h.generateAccessToken(helper.TokenInfo{
+		User_id:       token.UserId,
+		Username:      token.Username,
+		Role:          token.Role,
+		EmailVerified: token.EmailVerified,
+		PhoneVerified: token.PhoneVerified,
+		SessionId:     token.FamilyId,
+	})
+	if err != nil {
+		h.log.Error("error generating access token:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.respondTokens(c, &models.LoginRespond{Token: accessToken, RefreshToken: refreshToken})
+}
+
+// Logout revokes the current session and its refresh tokens
+func (h *Handler) Logout(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	_, err := h.storage.Session().RevokeSession(c.Request.Context(), &models.RevokeSession{
+		Id:     userInfo.SessionId,
+		UserId: userInfo.User_id,
+	})
+	if err != nil {
+		h.log.Error("error revoking refresh tokens:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userInfo.User_id,
+		EventType: models.AuthEventLogout,
+		Result:    models.AuthResultSuccess,
+	})
+
+	h.clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// issueTokens starts a new session for the user and returns the first
+// access and refresh token pair of it
+func (h *Handler) issueTokens(c *gin.Context, info helper.TokenInfo) (*models.LoginRespond, error) {
+	refreshToken, err := helper.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(config.RefreshTokenExpireTime)
+
+	info.SessionId, err = h.storage.Session().CreateSession(c.Request.Context(), &models.CreateSession{
+		UserId:    info.User_id,
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = h.storage.RefreshToken().CreateRefreshToken(c.Request.Context(), &models.CreateRefreshToken{
+		FamilyId:  info.SessionId,
+		UserId:    info.User_id,
+		TokenHash: helper.HashToken(refreshToken),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := h.generateAccessToken(info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LoginRespond{Token: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (h *Handler) generateAccessToken(info helper.TokenInfo) (string, error) {
 	m := make(map[string]interface{})
-	m["username"] = resp.Username
-	m["user_id"] = resp.User_id
+	m["username"] = info.Username
+	m["user_id"] = info.User_id
+	m["role"] = info.Role
+	m["email_verified"] = info.EmailVerified
+	m["phone_verified"] = info.PhoneVerified
+	m["sid"] = info.SessionId
+
+	return helper.GenerateJWT(m, config.TokenExpireTime, h.keys)
+}
 
-	token, _ := helper.GenerateJWT(m, config.TokenExpireTime, config.JWTSecretKey)
-	c.JSON(http.StatusOK, models.LoginRespond{Token: token})
+// JWKS publishes the public keys used to verify access tokens
+func (h *Handler) JWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"keys": h.keys.JWKS()})
 }
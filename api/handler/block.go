@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/storage"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BlockUser blocks the user in the path. The two users stop following each
+// other and can't see or interact with each other's posts and comments.
+func (h *Handler) BlockUser(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	target, ok := h.profileParam(c)
+	if !ok {
+		return
+	}
+	if target.ID == userInfo.User_id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "you can't block yourself"})
+		return
+	}
+
+	err := h.storage.Block().Block(c.Request.Context(), &models.Block{
+		BlockerId: userInfo.User_id,
+		BlockedId: target.ID,
+	})
+	if err != nil {
+		h.log.Error("error blocking user:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "blocked": true})
+}
+
+func (h *Handler) UnblockUser(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	target, ok := h.profileParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.storage.Block().Unblock(c.Request.Context(), &models.Block{
+		BlockerId: userInfo.User_id,
+		BlockedId: target.ID,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrNotBlocked) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error unblocking user:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "blocked": false})
+}
+
+// MuteUser hides the posts and comments of the user in the path from the
+// caller only, the muted user can still see and interact with the caller
+func (h *Handler) MuteUser(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	target, ok := h.profileParam(c)
+	if !ok {
+		return
+	}
+	if target.ID == userInfo.User_id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "you can't mute yourself"})
+		return
+	}
+
+	err := h.storage.Block().Mute(c.Request.Context(), &models.Mute{
+		MuterId: userInfo.User_id,
+		MutedId: target.ID,
+	})
+	if err != nil {
+		h.log.Error("error muting user:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "muted": true})
+}
+
+func (h *Handler) UnmuteUser(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	target, ok := h.profileParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.storage.Block().Unmute(c.Request.Context(), &models.Mute{
+		MuterId: userInfo.User_id,
+		MutedId: target.ID,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrNotMuted) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error unmuting user:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "muted": false})
+}
+
+// GetMyBlocks lists the users the caller has blocked
+func (h *Handler) GetMyBlocks(c *gin.Context) {
+	h.getBlocks(c, h.storage.Block().GetBlocks)
+}
+
+// GetMyMutes lists the users the caller has muted
+func (h *Handler) GetMyMutes(c *gin.Context) {
+	h.getBlocks(c, h.storage.Block().GetMutes)
+}
+
+func (h *Handler) getBlocks(c *gin.Context, list func(context.Context, *models.GetBlocksRequest) (*models.GetAllBlocks, error)) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page param"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit param"})
+		return
+	}
+
+	resp, err := list(c.Request.Context(), &models.GetBlocksRequest{
+		UserId: userInfo.User_id,
+		Page:   page,
+		Limit:  limit,
+	})
+	if err != nil {
+		h.log.Error("error get blocks:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
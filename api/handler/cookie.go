@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	accessTokenCookie  = "access_token"
+	refreshTokenCookie = "refresh_token"
+	csrfTokenCookie    = "csrf_token"
+	csrfTokenHeader    = "X-CSRF-Token"
+
+	// refreshTokenCookiePath keeps the refresh token from being sent to every endpoint
+	refreshTokenCookiePath = "/auth"
+)
+
+// cookieAuth reports whether tokens are also handed out and accepted as cookies
+func (h *Handler) cookieAuth() bool {
+	return h.cfg.AuthMode == config.AuthModeCookie || h.cfg.AuthMode == config.AuthModeBoth
+}
+
+// respondTokens writes a new token pair. In cookie modes the tokens are set as
+// HttpOnly cookies together with a CSRF token the client has to send back in
+// the X-CSRF-Token header; in cookie mode they are left out of the body.
+func (h *Handler) respondTokens(c *gin.Context, tokens *models.LoginRespond) {
+	if !h.cookieAuth() {
+		c.JSON(http.StatusOK, tokens)
+		return
+	}
+
+	csrfToken, err := helper.GenerateOpaqueToken(32)
+	if err != nil {
+		h.log.Error("error generating csrf token:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.setCookie(c, accessTokenCookie, tokens.Token, "/", config.TokenExpireTime, true)
+	h.setCookie(c, refreshTokenCookie, tokens.RefreshToken, refreshTokenCookiePath, config.RefreshTokenExpireTime, true)
+	// the CSRF cookie has to be readable by the web client
+	h.setCookie(c, csrfTokenCookie, csrfToken, "/", config.RefreshTokenExpireTime, false)
+
+	resp := models.LoginRespond{CSRFToken: csrfToken}
+	if h.cfg.AuthMode == config.AuthModeBoth {
+		resp.Token = tokens.Token
+		resp.RefreshToken = tokens.RefreshToken
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// clearAuthCookies removes the cookies set by respondTokens
+func (h *Handler) clearAuthCookies(c *gin.Context) {
+	if !h.cookieAuth() {
+		return
+	}
+
+	h.setCookie(c, accessTokenCookie, "", "/", -1, true)
+	h.setCookie(c, refreshTokenCookie, "", refreshTokenCookiePath, -1, true)
+	h.setCookie(c, csrfTokenCookie, "", "/", -1, false)
+}
+
+func (h *Handler) setCookie(c *gin.Context, name, value, path string, maxAge time.Duration, httpOnly bool) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   h.cfg.CookieDomain,
+		Secure:   h.cfg.CookieSecure,
+		HttpOnly: httpOnly,
+		SameSite: cookieSameSite(h.cfg.CookieSameSite),
+		MaxAge:   int(maxAge.Seconds()),
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	}
+
+	http.SetCookie(c.Writer, cookie)
+}
+
+func cookieSameSite(mode string) http.SameSite {
+	switch strings.ToLower(mode) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
+
+// validCSRF checks the double-submit CSRF token of a cookie authenticated
+// request. Safe methods don't change state and are let through.
+func validCSRF(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	cookie, err := c.Cookie(csrfTokenCookie)
+	if err != nil || cookie == "" {
+		return false
+	}
+
+	header := c.GetHeader(csrfTokenHeader)
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
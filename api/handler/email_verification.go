@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/pkg/mailer"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyEmail confirms the email address with the token from the verification link.
+// Tokens issued before verification still carry the old claim, so the client should refresh them.
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	_, err := h.storage.EmailVerification().VerifyEmail(c.Request.Context(), helper.HashToken(token))
+	if err != nil {
+		h.log.Error("error verifying email:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "verification link is invalid or expired"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+// ResendVerification sends a new verification link to the authenticated user
+func (h *Handler) ResendVerification(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	user, err := h.storage.User().GetUser(c.Request.Context(), &models.IdRequest{Id: userInfo.User_id})
+	if err != nil {
+		h.log.Error("error get user:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if user.EmailVerified {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is already verified"})
+		return
+	}
+	if user.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "account has no email"})
+		return
+	}
+
+	if err = h.sendEmailVerification(c, user.ID, user.Username, user.Email); err != nil {
+		h.log.Error("error sending verification mail:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "verification link has been sent"})
+}
+
+func (h *Handler) sendEmailVerification(c *gin.Context, userId, username, email string) error {
+	token, err := helper.GenerateOpaqueToken(32)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.storage.EmailVerification().CreateEmailVerification(c.Request.Context(), &models.CreateEmailVerification{
+		UserId:    userId,
+		TokenHash: helper.HashToken(token),
+		ExpiresAt: time.Now().Add(config.EmailVerifyExpireTime),
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.mail.Send(c.Request.Context(), mailer.Message{
+		To:      email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nConfirm your email with the link below. It expires in %s.\n\n%s/auth/verify?token=%s\n",
+			username, config.EmailVerifyExpireTime, h.cfg.AppBaseURL, url.QueryEscape(token),
+		),
+	})
+}
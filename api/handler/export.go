@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"archive/zip"
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportPageSize is the page size used to read paginated lists into an export
+const exportPageSize = 500
+
+// CreateExport starts building an archive with all the data of the caller.
+// The archive is built in the background and downloaded with GetExport.
+func (h *Handler) CreateExport(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	id, err := h.storage.Export().CreateExport(c.Request.Context(), userInfo.User_id)
+	if err != nil {
+		if errors.Is(err, storage.ErrExportPending) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error creating export:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userInfo.User_id,
+		EventType: models.AuthEventDataExport,
+		Result:    models.AuthResultSuccess,
+		Detail:    id,
+	})
+
+	go h.buildExport(id, userInfo)
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "export started", "id": id, "status": models.ExportPending})
+}
+
+// GetExport downloads a ready export. While it is being built, or when it
+// failed, the export status is returned instead.
+func (h *Handler) GetExport(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	export, err := h.storage.Export().GetExport(c.Request.Context(), &models.GetExport{
+		Id:     c.Param("id"),
+		UserId: userInfo.User_id,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrExportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error get export:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	switch export.Status {
+	case models.ExportPending:
+		c.JSON(http.StatusAccepted, export)
+	case models.ExportExpired:
+		c.JSON(http.StatusGone, gin.H{"error": "export has expired, please start a new one"})
+	case models.ExportReady:
+		c.FileAttachment(export.FilePath, fmt.Sprintf("geedbro-export-%s.zip", export.ID))
+	default:
+		c.JSON(http.StatusOK, export)
+	}
+}
+
+// buildExport writes the archive of an export and marks it as ready, or as
+// failed when anything goes wrong
+func (h *Handler) buildExport(id string, userInfo helper.TokenInfo) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.ExportBuildTimeout)
+	defer cancel()
+	// repos that serve "my" lists read the user from the context
+	ctx = context.WithValue(ctx, "user_info", userInfo)
+
+	path := filepath.Join(h.cfg.ExportDir, id+".zip")
+
+	err := h.writeExport(ctx, path, userInfo.User_id)
+	if err == nil {
+		err = h.storage.Export().CompleteExport(ctx, &models.CompleteExport{
+			Id:        id,
+			FilePath:  path,
+			ExpiresAt: time.Now().Add(h.cfg.ExportExpireTime),
+		})
+	}
+	if err != nil {
+		h.log.Error("error building export:", logger.Error(err), logger.String("export_id", id))
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			h.log.Error("error removing export:", logger.Error(err), logger.String("export_id", id))
+		}
+
+		err = h.storage.Export().FailExport(context.Background(), &models.FailExport{
+			Id:    id,
+			Error: "export could not be built",
+		})
+		if err != nil {
+			h.log.Error("error failing export:", logger.Error(err), logger.String("export_id", id))
+		}
+	}
+}
+
+// writeExport gathers the data of the user into a zip archive at path, one
+// JSON file per kind of data and the uploaded files under media/
+func (h *Handler) writeExport(ctx context.Context, path, userId string) error {
+	account, err := h.storage.User().GetUser(ctx, &models.IdRequest{Id: userId})
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	profile, err := h.storage.User().GetProfile(ctx, account.Username)
+	if err != nil {
+		return fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	page, limit, search := 0, 0, ""
+	posts, err := h.storage.Post().GetAllMyActivePost(ctx, &models.GetAllMyPostRequest{Page: &page, Limit: &limit, Search: &search})
+	if err != nil {
+		return fmt.Errorf("failed to get posts: %w", err)
+	}
+	comments, err := h.storage.Comment().GetMyComments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get comments: %w", err)
+	}
+	likes, err := h.storage.Like().GetUserLikes(ctx, userId)
+	if err != nil {
+		return err
+	}
+	commentLikes, err := h.storage.CommentLike().GetUserLikes(ctx, userId)
+	if err != nil {
+		return err
+	}
+	followers, err := exportFollows(ctx, h.storage.Follow().GetFollowers, userId)
+	if err != nil {
+		return err
+	}
+	following, err := exportFollows(ctx, h.storage.Follow().GetFollowing, userId)
+	if err != nil {
+		return err
+	}
+	blocks, err := exportBlocks(ctx, h.storage.Block().GetBlocks, userId)
+	if err != nil {
+		return err
+	}
+	mutes, err := exportBlocks(ctx, h.storage.Block().GetMutes, userId)
+	if err != nil {
+		return err
+	}
+	sessions, err := h.storage.Session().GetMySessions(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to get sessions: %w", err)
+	}
+	tokens, err := h.storage.PersonalToken().GetMyPersonalTokens(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to get personal tokens: %w", err)
+	}
+	passkeys, err := h.storage.Passkey().GetMyPasskeys(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("failed to get passkeys: %w", err)
+	}
+	events, err := h.exportAuthEvents(ctx, userId)
+	if err != nil {
+		return err
+	}
+	verification, err := h.storage.Verification().GetMyVerificationRequest(ctx, userId)
+	if err != nil && !errors.Is(err, storage.ErrVerificationNotFound) {
+		return fmt.Errorf("failed to get verification request: %w", err)
+	}
+
+	if err = os.MkdirAll(h.cfg.ExportDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create export dir: %w", err)
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer out.Close()
+
+	archive := zip.NewWriter(out)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"account.json", account},
+		{"profile.json", profile},
+		{"posts.json", posts.Posts},
+		{"comments.json", comments.Comments},
+		{"likes.json", likes},
+		{"comment_likes.json", commentLikes},
+		{"followers.json", followers},
+		{"following.json", following},
+		{"blocks.json", blocks},
+		{"mutes.json", mutes},
+		{"sessions.json", sessions.Sessions},
+		{"personal_tokens.json", tokens.Tokens},
+		{"passkeys.json", passkeys.Passkeys},
+		{"security_events.json", events},
+		{"verification.json", verification},
+	}
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", file.name, err)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(file.data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+
+	media := []string{profile.AvatarURL}
+	for _, post := range posts.Posts {
+		media = append(media, post.Photos...)
+	}
+	if err = addMediaFiles(archive, media); err != nil {
+		return err
+	}
+
+	if err = archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish export archive: %w", err)
+	}
+
+	return out.Close()
+}
+
+// addMediaFiles copies the uploaded files behind the links into the archive.
+// Links that aren't local uploads or whose file is gone are skipped.
+func addMediaFiles(archive *zip.Writer, links []string) error {
+	added := make(map[string]bool)
+	for _, link := range links {
+		if !strings.HasPrefix(link, "/media/") || strings.Contains(link, "..") || added[link] {
+			continue
+		}
+		added[link] = true
+
+		src, err := os.Open("." + link)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return fmt.Errorf("failed to open %s: %w", link, err)
+		}
+
+		w, err := archive.Create(strings.TrimPrefix(link, "/"))
+		if err == nil {
+			_, err = io.Copy(w, src)
+		}
+		src.Close()
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", link, err)
+		}
+	}
+
+	return nil
+}
+
+func exportFollows(ctx context.Context, list func(context.Context, *models.GetFollowsRequest) (*models.GetAllFollows, error), userId string) ([]models.FollowUser, error) {
+	users := make([]models.FollowUser, 0)
+	for page := 1; ; page++ {
+		resp, err := list(ctx, &models.GetFollowsRequest{UserId: userId, Page: page, Limit: exportPageSize})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get follows: %w", err)
+		}
+		users = append(users, resp.Users...)
+		if len(resp.Users) < exportPageSize {
+			return users, nil
+		}
+	}
+}
+
+func exportBlocks(ctx context.Context, list func(context.Context, *models.GetBlocksRequest) (*models.GetAllBlocks, error), userId string) ([]models.BlockedUser, error) {
+	users := make([]models.BlockedUser, 0)
+	for page := 1; ; page++ {
+		resp, err := list(ctx, &models.GetBlocksRequest{UserId: userId, Page: page, Limit: exportPageSize})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get blocks: %w", err)
+		}
+		users = append(users, resp.Users...)
+		if len(resp.Users) < exportPageSize {
+			return users, nil
+		}
+	}
+}
+
+func (h *Handler) exportAuthEvents(ctx context.Context, userId string) ([]models.AuthEvent, error) {
+	events := make([]models.AuthEvent, 0)
+	for page := 1; ; page++ {
+		resp, err := h.storage.AuthEvent().GetAuthEvents(ctx, &models.GetAuthEventsRequest{UserId: userId, Page: page, Limit: exportPageSize})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get auth events: %w", err)
+		}
+		events = append(events, resp.Events...)
+		if len(resp.Events) < exportPageSize {
+			return events, nil
+		}
+	}
+}
+
+// cleanupExports removes the archives of expired exports and fails exports
+// whose build didn't finish in time
+func (h *Handler) cleanupExports(ctx context.Context) {
+	paths, err := h.storage.Export().ExpireExports(ctx, time.Now().Add(-config.ExportBuildTimeout))
+	if err != nil {
+		h.log.Error("error expiring exports:", logger.Error(err))
+		return
+	}
+
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			h.log.Error("error removing export:", logger.Error(err), logger.String("path", path))
+		}
+	}
+}
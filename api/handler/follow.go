@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/storage"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FollowUser follows the user in the path. Following someone twice is not an error.
+// A private account gets a follow request to approve instead.
+func (h *Handler) FollowUser(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	target, ok := h.profileParam(c)
+	if !ok {
+		return
+	}
+	if target.ID == userInfo.User_id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "you can't follow yourself"})
+		return
+	}
+
+	blocked, err := h.storage.Block().IsBlocked(c.Request.Context(), userInfo.User_id, target.ID)
+	if err != nil {
+		h.log.Error("error checking block:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if blocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": storage.ErrBlocked.Error()})
+		return
+	}
+
+	follow := &models.Follow{
+		FollowerId:  userInfo.User_id,
+		FollowingId: target.ID,
+	}
+
+	// private accounts approve their followers, ask them unless already following
+	if target.IsPrivate {
+		following, err := h.storage.Follow().IsFollowing(c.Request.Context(), userInfo.User_id, target.ID)
+		if err != nil {
+			h.log.Error("error checking follow:", logger.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+		if !following {
+			_, err = h.storage.Follow().RequestFollow(c.Request.Context(), follow)
+			if err != nil {
+				h.log.Error("error requesting follow:", logger.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+				return
+			}
+
+			c.JSON(http.StatusAccepted, gin.H{"message": "follow request sent", "following": false, "requested": true})
+			return
+		}
+	}
+
+	_, err = h.storage.Follow().Follow(c.Request.Context(), follow)
+	if err != nil {
+		h.log.Error("error following user:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	mutual, err := h.storage.Follow().IsMutual(c.Request.Context(), userInfo.User_id, target.ID)
+	if err != nil {
+		h.log.Error("error checking mutual follow:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "following": true, "is_mutual": mutual})
+}
+
+// UnfollowUser stops following the user in the path, or withdraws the pending
+// follow request sent to them
+func (h *Handler) UnfollowUser(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	target, ok := h.profileParam(c)
+	if !ok {
+		return
+	}
+
+	follow := &models.Follow{
+		FollowerId:  userInfo.User_id,
+		FollowingId: target.ID,
+	}
+	err := h.storage.Follow().Unfollow(c.Request.Context(), follow)
+	if errors.Is(err, storage.ErrNotFollowing) {
+		if cancelErr := h.storage.Follow().CancelFollowRequest(c.Request.Context(), follow); !errors.Is(cancelErr, storage.ErrFollowRequestNotFound) {
+			err = cancelErr
+		}
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFollowing) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error unfollowing user:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "following": false})
+}
+
+// GetFollowers lists who follows the user in the path
+func (h *Handler) GetFollowers(c *gin.Context) {
+	h.getFollows(c, h.storage.Follow().GetFollowers)
+}
+
+// GetFollowing lists who the user in the path follows
+func (h *Handler) GetFollowing(c *gin.Context) {
+	h.getFollows(c, h.storage.Follow().GetFollowing)
+}
+
+func (h *Handler) getFollows(c *gin.Context, list func(context.Context, *models.GetFollowsRequest) (*models.GetAllFollows, error)) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page param"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit param"})
+		return
+	}
+
+	target, ok := h.profileParam(c)
+	if !ok {
+		return
+	}
+
+	resp, err := list(c.Request.Context(), &models.GetFollowsRequest{
+		UserId: target.ID,
+		Page:   page,
+		Limit:  limit,
+	})
+	if err != nil {
+		h.log.Error("error get follows:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateMyPrivacy makes the account of the caller private or public. Making
+// it public approves the pending follow requests.
+func (h *Handler) UpdateMyPrivacy(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	var req models.UpdatePrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+
+	err := h.storage.User().UpdatePrivacy(c.Request.Context(), &models.UpdatePrivacy{
+		UserId:    userInfo.User_id,
+		IsPrivate: *req.IsPrivate,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error updating privacy:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "is_private": *req.IsPrivate})
+}
+
+// GetMyFollowRequests lists the pending follow requests sent to the caller
+func (h *Handler) GetMyFollowRequests(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page param"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit param"})
+		return
+	}
+
+	resp, err := h.storage.Follow().GetFollowRequests(c.Request.Context(), &models.GetFollowsRequest{
+		UserId: userInfo.User_id,
+		Page:   page,
+		Limit:  limit,
+	})
+	if err != nil {
+		h.log.Error("error get follow requests:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ApproveFollowRequest lets the requester follow the caller
+func (h *Handler) ApproveFollowRequest(c *gin.Context) {
+	h.reviewFollowRequest(c, h.storage.Follow().ApproveFollowRequest, "approved")
+}
+
+// RejectFollowRequest drops the request without telling the requester
+func (h *Handler) RejectFollowRequest(c *gin.Context) {
+	h.reviewFollowRequest(c, h.storage.Follow().RejectFollowRequest, "rejected")
+}
+
+func (h *Handler) reviewFollowRequest(c *gin.Context, review func(context.Context, *models.ReviewFollowRequest) error, status string) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	err := review(c.Request.Context(), &models.ReviewFollowRequest{
+		Id:     c.Param("id"),
+		UserId: userInfo.User_id,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrFollowRequestNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error reviewing follow request:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "status": status})
+}
+
+// profileParam looks up the active user named in the :username path param.
+// It answers 404 and returns false when there is none.
+func (h *Handler) profileParam(c *gin.Context) (*models.Profile, bool) {
+	profile, err := h.storage.User().GetProfile(c.Request.Context(), c.Param("username"))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		h.log.Error("error get profile:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return nil, false
+	}
+
+	return profile, true
+}
@@ -1,15 +1,37 @@
 package handler
 
 import (
+	"auth/config"
+	"auth/pkg/helper"
 	"auth/pkg/logger"
+	"auth/pkg/mailer"
+	"auth/pkg/oidc"
+	"auth/pkg/sms"
+	"auth/pkg/validation"
+	"auth/pkg/webauthn"
 	"auth/storage"
 )
 
 type Handler struct {
-	storage storage.StorageI
-	log     logger.LoggerI
+	cfg       config.Config
+	storage   storage.StorageI
+	log       logger.LoggerI
+	keys      *helper.KeySet
+	mail      mailer.MailerI
+	validate  *validation.Validator
+	providers map[string]*oidc.Provider
+	sms       sms.SMSSender
+	cipher    *helper.FieldCipher
+	webauthn  *webauthn.RelyingParty
+	files     *helper.Service
+
+	// dummyPasswordHash is compared against when the username doesn't exist,
+	// it is made with the configured algorithm so both take as long
+	dummyPasswordHash []byte
 }
 
-func NewHandler(strg storage.StorageI, loger logger.LoggerI) *Handler {
-	return &Handler{storage: strg, log: loger}
+func NewHandler(cfg config.Config, strg storage.StorageI, loger logger.LoggerI, keys *helper.KeySet, mail mailer.MailerI, validate *validation.Validator, providers map[string]*oidc.Provider, smsSender sms.SMSSender, cipher *helper.FieldCipher, relyingParty *webauthn.RelyingParty, files *helper.Service) *Handler {
+	dummyPasswordHash, _ := helper.GeneratePasswordHash(cfg, "dummy-password")
+
+	return &Handler{cfg: cfg, storage: strg, log: loger, keys: keys, mail: mail, validate: validate, providers: providers, sms: smsSender, cipher: cipher, webauthn: relyingParty, files: files, dummyPasswordHash: dummyPasswordHash}
 }
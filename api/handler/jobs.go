@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"auth/config"
+	"context"
+	"time"
+)
+
+// StartJobs runs the periodic background jobs until ctx is done
+func (h *Handler) StartJobs(ctx context.Context) {
+	go h.runEvery(ctx, config.ExportCleanupInterval, h.cleanupExports)
+	go h.runEvery(ctx, config.AccountPurgeInterval, h.purgeDeletedAccounts)
+}
+
+// runEvery runs job right away and then once every interval
+func (h *Handler) runEvery(ctx context.Context, interval time.Duration, job func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/logger"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func loginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+func loginAccountKey(userId string) string {
+	return "user:" + userId
+}
+
+// loginNameKey counts password login failures per submitted username, so
+// unknown usernames lock the same way existing accounts do
+func loginNameKey(username string) string {
+	return "login:" + strings.ToLower(username)
+}
+
+// loginDelay is how long a client has to wait after its n-th consecutive failure.
+// The first two failures are free, then the delay doubles up to config.LoginMaxDelay.
+func loginDelay(failures int) time.Duration {
+	if failures < 3 {
+		return 0
+	}
+
+	delay := time.Duration(math.Pow(2, float64(failures-3))) * time.Second
+	if delay > config.LoginMaxDelay || delay <= 0 {
+		return config.LoginMaxDelay
+	}
+	return delay
+}
+
+// rejectLockedLogin answers 429 and returns true if the key is locked or still
+// has to wait after its last failure
+func (h *Handler) rejectLockedLogin(c *gin.Context, key string) bool {
+	failure, err := h.storage.LoginFailure().GetLoginFailure(c.Request.Context(), key)
+	if err != nil {
+		h.log.Error("error get login failures:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return true
+	}
+
+	now := time.Now()
+	retryAt := failure.LastFailedAt.Add(loginDelay(failure.FailedCount))
+	if failure.LockedUntil.After(retryAt) {
+		retryAt = failure.LockedUntil
+	}
+
+	if !retryAt.After(now) {
+		return false
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAt.Sub(now).Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed login attempts, try again later"})
+	return true
+}
+
+func (h *Handler) registerLoginFailure(c *gin.Context, key string, maxFailures int) {
+	failure, err := h.storage.LoginFailure().RegisterLoginFailure(c.Request.Context(), &models.RegisterLoginFailure{
+		Key:          key,
+		MaxFailures:  maxFailures,
+		Window:       config.LoginFailureWindow,
+		LockDuration: config.LoginLockDuration,
+	})
+	if err != nil {
+		h.log.Error("error registering login failure:", logger.Error(err))
+		return
+	}
+
+	if !failure.LockedUntil.IsZero() {
+		h.log.Warn("login locked", logger.String("key", key), logger.Int("failures", failure.FailedCount))
+	}
+}
+
+// UnlockUser clears the failed login counters of an account
+func (h *Handler) UnlockUser(c *gin.Context) {
+	id := c.Param("id")
+
+	user, err := h.storage.User().GetUser(c.Request.Context(), &models.IdRequest{Id: id})
+	if err != nil {
+		h.log.Error("error get user:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	for _, key := range []string{loginAccountKey(id), loginNameKey(user.Username)} {
+		err = h.storage.LoginFailure().ResetLoginFailures(c.Request.Context(), key)
+		if err != nil {
+			h.log.Error("error unlocking user:", logger.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "unlocked user id": id})
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/pkg/mailer"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// magicLinkDeviceCookie binds a sign-in link to the device that requested it
+	magicLinkDeviceCookie = "magic_link_device"
+	magicLinkCookiePath   = "/auth/magic-link"
+)
+
+// SendMagicLink mails a single use sign-in link. Like ForgotPassword it answers
+// the same way for unknown addresses and for addresses over the rate limit.
+func (h *Handler) SendMagicLink(c *gin.Context) {
+	var req models.MagicLinkRequest
+	err := c.ShouldBindJSON(&req)
+	req.Email = strings.TrimSpace(req.Email)
+	if err != nil || !helper.IsValidEmail(req.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email"})
+		return
+	}
+
+	// the device secret is kept when the same device asks again, so earlier links keep working
+	device, err := c.Cookie(magicLinkDeviceCookie)
+	if err != nil || device == "" {
+		device, err = helper.GenerateOpaqueToken(32)
+		if err != nil {
+			h.log.Error("error generating device token:", logger.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+	}
+	h.setCookie(c, magicLinkDeviceCookie, device, magicLinkCookiePath, config.MagicLinkExpireTime, true)
+
+	resp := gin.H{"message": "if the email is registered, a sign-in link has been sent"}
+
+	user, err := h.storage.User().GetByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		h.log.Info("magic link for unknown email:", logger.Error(err))
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	count, err := h.storage.MagicLink().CountMagicLinks(c.Request.Context(), user.Email, time.Now().Add(-config.MagicLinkRateWindow))
+	if err != nil {
+		h.log.Error("error counting magic links:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if count >= config.MagicLinkMaxPerWindow {
+		h.log.Warn("magic link rate limit reached", logger.String("user_id", user.User_id))
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	token, err := helper.GenerateOpaqueToken(32)
+	if err != nil {
+		h.log.Error("error generating magic link token:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	err = h.storage.MagicLink().CreateMagicLink(c.Request.Context(), &models.CreateMagicLink{
+		UserId:     user.User_id,
+		Email:      user.Email,
+		TokenHash:  helper.HashToken(token),
+		DeviceHash: helper.HashToken(device),
+		ExpiresAt:  time.Now().Add(config.MagicLinkExpireTime),
+	})
+	if err != nil {
+		h.log.Error("error creating magic link:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	err = h.mail.Send(c.Request.Context(), mailer.Message{
+		To:      user.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to sign in. It works once, only in the browser you asked from, and expires in %s.\n\n%s/auth/magic-link/callback?token=%s\n\nIf you didn't ask for it, ignore this email.\n",
+			user.Username, config.MagicLinkExpireTime, h.cfg.AppBaseURL, url.QueryEscape(token),
+		),
+	})
+	if err != nil {
+		h.log.Error("error sending magic link mail:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// MagicLinkCallback exchanges a sign-in link for the regular tokens
+func (h *Handler) MagicLinkCallback(c *gin.Context) {
+	token := c.Query("token")
+	device, _ := c.Cookie(magicLinkDeviceCookie)
+	if token == "" || device == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sign-in link is invalid, expired or was opened on another device"})
+		return
+	}
+
+	user, err := h.storage.MagicLink().UseMagicLink(c.Request.Context(), &models.UseMagicLink{
+		TokenHash:  helper.HashToken(token),
+		DeviceHash: helper.HashToken(device),
+	})
+	if err != nil {
+		h.log.Error("error using magic link:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sign-in link is invalid, expired or was opened on another device"})
+		return
+	}
+
+	h.completeLogin(c, user, models.LoginMethodMagicLink)
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthMiddleWare is a middleware function for authentication.
+// Personal access tokens are refused, use AuthWithScope for routes open to them.
+func (h *Handler) AuthMiddleWare(c *gin.Context) {
+	h.authenticate(c, "")
+}
+
+// AuthWithScope authenticates like AuthMiddleWare but also accepts personal
+// access tokens that were given the scope
+func (h *Handler) AuthWithScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.authenticate(c, scope)
+	}
+}
+
+// OptionalAuthWithScope authenticates like AuthWithScope when the request
+// carries a token and lets anonymous requests through otherwise
+func (h *Handler) OptionalAuthWithScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token, _ := h.requestToken(c); token == "" {
+			c.Next()
+			return
+		}
+		h.authenticate(c, scope)
+	}
+}
+
+// viewerId returns the id of the authenticated user, empty for anonymous requests
+func viewerId(c *gin.Context) string {
+	if userInfo, ok := c.Get("user_info"); ok {
+		return userInfo.(helper.TokenInfo).User_id
+	}
+	return ""
+}
+
+func (h *Handler) authenticate(c *gin.Context, scope string) {
+	token, fromCookie := h.requestToken(c)
+
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"code":    "UNAUTHORIZED!",
+			"message": "Token not found...",
+		})
+		c.Abort()
+		return
+	}
+
+	if fromCookie && !validCSRF(c) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"code":    "INVALID CSRF TOKEN!",
+			"message": "CSRF token is missing or doesn't match...",
+		})
+		c.Abort()
+		return
+	}
+
+	var (
+		userInfo helper.TokenInfo
+		ok       bool
+	)
+	if strings.HasPrefix(token, config.PersonalTokenPrefix) {
+		userInfo, ok = h.personalTokenInfo(c, token)
+		if !ok {
+			return
+		}
+
+		if scope == "" || !userInfo.HasScope(scope) {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"code":    "FORBIDDEN!",
+				"message": "Token doesn't have the scope required by this endpoint...",
+			})
+			c.Abort()
+			return
+		}
+	} else {
+		userInfo, ok = h.sessionTokenInfo(c, token)
+		if !ok {
+			return
+		}
+	}
+
+	c.Set("user_info", userInfo)
+	c.Next()
+}
+
+// requestToken returns the token from the Authorization header, with or without
+// the Bearer prefix, or in cookie modes from the access token cookie
+func (h *Handler) requestToken(c *gin.Context) (token string, fromCookie bool) {
+	if header := c.GetHeader("Authorization"); header != "" {
+		token, err := helper.ExtractToken(header)
+		if err != nil {
+			// older clients send the bare token
+			return header, false
+		}
+		return token, false
+	}
+
+	if h.cookieAuth() {
+		if token, err := c.Cookie(accessTokenCookie); err == nil {
+			return token, true
+		}
+	}
+
+	return "", false
+}
+
+// sessionTokenInfo validates a JWT access token and its session
+func (h *Handler) sessionTokenInfo(c *gin.Context, token string) (helper.TokenInfo, bool) {
+	userInfo, err := helper.ParseClaims(token, h.keys)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"code":    "INVALID TOKEN!",
+			"message": "Provided token is not valid...",
+		})
+		c.Abort()
+		return userInfo, false
+	}
+
+	active, err := h.storage.Session().TouchSession(c.Request.Context(), userInfo.SessionId)
+	if err != nil {
+		h.log.Error("error checking session:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Abort()
+		return userInfo, false
+	}
+	if !active {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"code":    "SESSION REVOKED!",
+			"message": "Session has been revoked, please login again...",
+		})
+		c.Abort()
+		return userInfo, false
+	}
+
+	return userInfo, true
+}
+
+// personalTokenInfo validates a personal access token
+func (h *Handler) personalTokenInfo(c *gin.Context, token string) (helper.TokenInfo, bool) {
+	pat, err := h.storage.PersonalToken().GetPersonalTokenByHash(c.Request.Context(), helper.HashToken(token))
+	if err != nil || pat.Revoked || (!pat.ExpiresAt.IsZero() && time.Now().After(pat.ExpiresAt)) {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"code":    "INVALID TOKEN!",
+			"message": "Provided token is not valid...",
+		})
+		c.Abort()
+		return helper.TokenInfo{}, false
+	}
+
+	if err = h.storage.PersonalToken().TouchPersonalToken(c.Request.Context(), pat.ID); err != nil {
+		h.log.Error("error updating personal access token:", logger.Error(err))
+	}
+
+	return helper.TokenInfo{
+		Username:      pat.Username,
+		User_id:       pat.UserId,
+		Role:          pat.Role,
+		EmailVerified: pat.EmailVerified,
+		PhoneVerified: pat.PhoneVerified,
+		TokenId:       pat.ID,
+		Scopes:        pat.Scopes,
+	}, true
+}
@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/pkg/oidc"
+	"auth/pkg/validation"
+	"auth/storage"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const oidcStateCookie = "oidc_state"
+
+// errIdentityEmailTaken is returned when the provider's email belongs to a local
+// account that can't be linked automatically
+var errIdentityEmailTaken = errors.New("an account with this email already exists, log in with a password first")
+
+// GetOIDCProviders lists the identity providers users can sign in with
+func (h *Handler) GetOIDCProviders(c *gin.Context) {
+	names := make([]string, 0, len(h.providers))
+	for name := range h.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c.JSON(http.StatusOK, models.OIDCProvidersRespond{Providers: names})
+}
+
+// OIDCLogin redirects the user to the identity provider. The state is bound to
+// the browser with a short lived cookie that the callback checks.
+func (h *Handler) OIDCLogin(c *gin.Context) {
+	provider, ok := h.providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown identity provider"})
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := helper.GenerateOpaqueToken(32)
+		if err != nil {
+			h.log.Error("error generating oidc state:", logger.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+		values[i] = value
+	}
+	state, nonce, codeVerifier := values[0], values[1], values[2]
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, oidc.CodeChallenge(codeVerifier))
+	if err != nil {
+		h.log.Error("error building oidc auth url:", logger.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider is unavailable"})
+		return
+	}
+
+	err = h.storage.Identity().CreateOIDCState(c.Request.Context(), &models.CreateOIDCState{
+		Provider:     provider.Name(),
+		StateHash:    helper.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(config.OIDCStateExpireTime),
+	})
+	if err != nil {
+		h.log.Error("error creating oidc state:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.setCookie(c, oidcStateCookie, state, "/auth/oidc", config.OIDCStateExpireTime, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback finishes the authorization code flow. The external identity is
+// linked to a local user, which is created on the first login.
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	provider, ok := h.providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown identity provider"})
+		return
+	}
+
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "identity provider denied the login: " + errCode})
+		return
+	}
+
+	state, code := c.Query("state"), c.Query("code")
+	cookie, _ := c.Cookie(oidcStateCookie)
+	if state == "" || code == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookie)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid login state"})
+		return
+	}
+	h.setCookie(c, oidcStateCookie, "", "/auth/oidc", -1, true)
+
+	saved, err := h.storage.Identity().UseOIDCState(c.Request.Context(), helper.HashToken(state))
+	if err != nil || saved.Provider != provider.Name() {
+		h.log.Error("error using oidc state:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid login state"})
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), code, saved.CodeVerifier, saved.Nonce)
+	if err != nil {
+		h.log.Error("error exchanging oidc code:", logger.Error(err))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "identity provider login failed"})
+		return
+	}
+
+	user, err := h.identityUser(c, provider.Name(), claims)
+	if err != nil {
+		if errors.Is(err, errIdentityEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error getting identity user:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.completeLogin(c, user, models.LoginMethodOIDC+":"+provider.Name())
+}
+
+// identityUser returns the user linked to the external identity. Without a link
+// the identity is linked to the local account with the same verified email, or
+// a new user is created.
+func (h *Handler) identityUser(c *gin.Context, provider string, claims *oidc.Claims) (*models.LoginDataRespond, error) {
+	user, err := h.storage.Identity().GetUserByIdentity(c.Request.Context(), provider, claims.Subject)
+	if err == nil || !errors.Is(err, storage.ErrUserNotFound) {
+		return user, err
+	}
+
+	email := ""
+	if claims.EmailVerified {
+		email = strings.TrimSpace(claims.Email)
+	}
+
+	if email != "" {
+		user, err = h.storage.User().GetByEmail(c.Request.Context(), email)
+		if err == nil {
+			// an unverified local email could have been registered by anyone
+			if !user.EmailVerified {
+				return nil, errIdentityEmailTaken
+			}
+
+			err = h.storage.Identity().LinkIdentity(c.Request.Context(), &models.LinkIdentity{
+				UserId:   user.User_id,
+				Provider: provider,
+				Subject:  claims.Subject,
+				Email:    email,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return h.storage.Identity().GetUserByIdentity(c.Request.Context(), provider, claims.Subject)
+		}
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			return nil, err
+		}
+	}
+
+	// the password is random and never shown, the user can set one with password reset
+	password, err := helper.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPass, err := helper.GeneratePasswordHash(h.cfg, password)
+	if err != nil {
+		return nil, err
+	}
+
+	base := identityUsername(claims)
+	for attempt := 0; attempt < 5; attempt++ {
+		username := base
+		if attempt > 0 || !h.validUsername(username) {
+			username, err = h.randomUsername(base)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		_, err = h.storage.Identity().CreateIdentityUser(c.Request.Context(), &models.CreateIdentityUser{
+			Username: username,
+			Email:    email,
+			Password: string(hashedPass),
+			Provider: provider,
+			Subject:  claims.Subject,
+		})
+		if errors.Is(err, storage.ErrUsernameTaken) {
+			continue
+		}
+		if errors.Is(err, storage.ErrEmailTaken) {
+			return nil, errIdentityEmailTaken
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return h.storage.Identity().GetUserByIdentity(c.Request.Context(), provider, claims.Subject)
+	}
+
+	return nil, errors.New("failed to find a free username")
+}
+
+func (h *Handler) validUsername(username string) bool {
+	var errs validation.Errors
+	h.validate.Username(&errs, "username", username)
+	return errs.Empty()
+}
+
+// randomUsername appends four random digits to the base, shortened to fit
+func (h *Handler) randomUsername(base string) (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(10000))
+	if err != nil {
+		return "", err
+	}
+
+	if max := h.cfg.UsernameMaxLength - 5; len(base) > max {
+		base = base[:max]
+	}
+	return fmt.Sprintf("%s_%04d", base, n.Int64()), nil
+}
+
+// identityUsername builds a username suggestion from the preferred username or
+// the local part of the email, keeping only the characters usernames allow
+func identityUsername(claims *oidc.Claims) string {
+	name := claims.PreferredUsername
+	if name == "" {
+		name = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		case r == '.' || r == '-':
+			b.WriteRune('_')
+		}
+	}
+
+	username := b.String()
+	if username == "" || username[0] < 'a' || username[0] > 'z' {
+		username = "user" + username
+	}
+	return username
+}
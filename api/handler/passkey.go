@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/pkg/webauthn"
+	"auth/storage"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BeginPasskeyRegistration returns the options for navigator.credentials.create()
+func (h *Handler) BeginPasskeyRegistration(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	user, err := h.storage.User().GetUser(c.Request.Context(), &models.IdRequest{Id: userInfo.User_id})
+	if err != nil {
+		h.log.Error("error get user:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	exclude, err := h.storage.Passkey().GetPasskeyCredentialIds(c.Request.Context(), userInfo.User_id)
+	if err != nil {
+		h.log.Error("error get passkeys:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	challenge, ok := h.newWebAuthnChallenge(c, userInfo.User_id, models.PasskeyRegistration)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": h.webauthn.CreationOptions(challenge, webauthn.User{
+		ID:          user.ID,
+		Name:        user.Username,
+		DisplayName: user.Username,
+	}, exclude)})
+}
+
+// FinishPasskeyRegistration verifies the credential created by the browser and stores it
+func (h *Handler) FinishPasskeyRegistration(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	var req models.PasskeyRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		req.Name = "Passkey"
+	}
+	if len([]rune(req.Name)) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be at most 100 characters"})
+		return
+	}
+
+	credential, err := h.webauthn.VerifyRegistration(&req.Credential)
+	if err != nil {
+		h.log.Error("error verifying passkey registration:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "passkey registration failed"})
+		return
+	}
+
+	userId, err := h.storage.Passkey().UseWebAuthnChallenge(c.Request.Context(), helper.HashToken(credential.Challenge), models.PasskeyRegistration)
+	if err != nil || userId != userInfo.User_id {
+		h.log.Error("error using webauthn challenge:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "passkey registration failed"})
+		return
+	}
+
+	id, err := h.storage.Passkey().CreatePasskey(c.Request.Context(), &models.CreatePasskey{
+		UserId:       userInfo.User_id,
+		Name:         req.Name,
+		CredentialId: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    int64(credential.SignCount),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrPasskeyExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error creating passkey:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userInfo.User_id,
+		EventType: models.AuthEventPasskeyRegister,
+		Result:    models.AuthResultSuccess,
+		Detail:    id,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"message": "created", "id": id})
+}
+
+func (h *Handler) GetMyPasskeys(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	resp, err := h.storage.Passkey().GetMyPasskeys(c.Request.Context(), userInfo.User_id)
+	if err != nil {
+		h.log.Error("error get passkeys:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) DeletePasskey(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	resp, err := h.storage.Passkey().DeletePasskey(c.Request.Context(), &models.DeletePasskey{
+		Id:     c.Param("id"),
+		UserId: userInfo.User_id,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrPasskeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error deleting passkey:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userInfo.User_id,
+		EventType: models.AuthEventPasskeyRemove,
+		Result:    models.AuthResultSuccess,
+		Detail:    resp,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "deleted passkey id": resp})
+}
+
+// BeginPasskeyLogin returns the options for navigator.credentials.get()
+func (h *Handler) BeginPasskeyLogin(c *gin.Context) {
+	challenge, ok := h.newWebAuthnChallenge(c, "", models.PasskeyLogin)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": h.webauthn.RequestOptions(challenge)})
+}
+
+// FinishPasskeyLogin verifies the assertion signed by the authenticator and
+// logs the owner of the passkey in. A passkey used with user verification
+// counts as two factors, so no TOTP code is asked for.
+func (h *Handler) FinishPasskeyLogin(c *gin.Context) {
+	var req webauthn.AssertionResponse
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+
+	ipKey := loginIPKey(c.ClientIP())
+	if h.rejectLockedLogin(c, ipKey) {
+		h.recordLoginFailure(c, "", "ip_locked")
+		return
+	}
+
+	credentialId, err := webauthn.DecodeID(req.RawID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid credential id"})
+		return
+	}
+
+	credential, err := h.storage.Passkey().GetPasskeyByCredentialId(c.Request.Context(), credentialId)
+	if err != nil {
+		if !errors.Is(err, storage.ErrPasskeyNotFound) {
+			h.log.Error("error get passkey:", logger.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+		h.registerLoginFailure(c, ipKey, config.LoginMaxIPFailures)
+		h.recordLoginFailure(c, "", "unknown_passkey")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "passkey login failed"})
+		return
+	}
+
+	assertion, err := h.webauthn.VerifyAssertion(&req, credential.PublicKey)
+	if err == nil && assertion.UserHandle != "" && assertion.UserHandle != credential.UserId {
+		err = errors.New("user handle doesn't match the passkey owner")
+	}
+	if err != nil {
+		h.log.Error("error verifying passkey assertion:", logger.Error(err))
+		h.registerLoginFailure(c, ipKey, config.LoginMaxIPFailures)
+		h.recordLoginFailure(c, credential.UserId, "invalid_passkey_assertion")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "passkey login failed"})
+		return
+	}
+
+	if _, err = h.storage.Passkey().UseWebAuthnChallenge(c.Request.Context(), helper.HashToken(assertion.Challenge), models.PasskeyLogin); err != nil {
+		h.log.Error("error using webauthn challenge:", logger.Error(err))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "passkey login failed"})
+		return
+	}
+
+	newCount := int64(assertion.SignCount)
+	if err = webauthn.CheckSignCount(uint32(credential.SignCount), assertion.SignCount); err != nil {
+		h.log.Warn("passkey signature counter went back", logger.String("passkey_id", credential.ID))
+		h.recordLoginFailure(c, credential.UserId, "passkey_counter_mismatch")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "passkey login failed"})
+		return
+	}
+
+	user, err := h.storage.Passkey().UsePasskey(c.Request.Context(), &models.UsePasskey{
+		Id:           credential.ID,
+		OldSignCount: credential.SignCount,
+		SignCount:    newCount,
+	})
+	if err != nil {
+		h.log.Error("error using passkey:", logger.Error(err))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "passkey login failed"})
+		return
+	}
+
+	if assertion.UserVerified {
+		user.TwoFactorEnabled = false
+	}
+
+	h.completeLogin(c, user, models.LoginMethodPasskey)
+}
+
+// newWebAuthnChallenge stores a fresh challenge for the ceremony and returns it.
+// It answers 500 and returns false on failure.
+func (h *Handler) newWebAuthnChallenge(c *gin.Context, userId, ceremony string) (string, bool) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		h.log.Error("error generating webauthn challenge:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return "", false
+	}
+
+	err = h.storage.Passkey().CreateWebAuthnChallenge(c.Request.Context(), &models.CreateWebAuthnChallenge{
+		UserId:        userId,
+		Ceremony:      ceremony,
+		ChallengeHash: helper.HashToken(challenge),
+		ExpiresAt:     time.Now().Add(config.WebAuthnChallengeExpireTime),
+	})
+	if err != nil {
+		h.log.Error("error creating webauthn challenge:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return "", false
+	}
+
+	return challenge, true
+}
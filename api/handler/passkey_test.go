@@ -0,0 +1,405 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/pkg/webauthn"
+	"auth/pkg/webauthn/webauthntest"
+	"auth/storage"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+	testUserId = "user-1"
+)
+
+// passkeyStorage keeps the challenges and the passkey of one user in memory.
+// A challenge is removed when used, like the used_at column does.
+type passkeyStorage struct {
+	storage.StorageI
+	storage.PasskeysI
+	storage.UsersI
+	storage.AuthEventsI
+	storage.LoginFailuresI
+
+	challenges map[string]models.CreateWebAuthnChallenge
+	passkey    *models.PasskeyCredential
+	credential []byte
+	user       models.LoginDataRespond
+}
+
+func newPasskeyStorage() *passkeyStorage {
+	return &passkeyStorage{
+		challenges: make(map[string]models.CreateWebAuthnChallenge),
+		user: models.LoginDataRespond{
+			User_id:          testUserId,
+			Username:         "alice",
+			TwoFactorEnabled: true,
+		},
+	}
+}
+
+func (s *passkeyStorage) Passkey() storage.PasskeysI           { return s }
+func (s *passkeyStorage) User() storage.UsersI                 { return s }
+func (s *passkeyStorage) AuthEvent() storage.AuthEventsI       { return s }
+func (s *passkeyStorage) LoginFailure() storage.LoginFailuresI { return s }
+
+func (s *passkeyStorage) GetUser(c context.Context, req *models.IdRequest) (*models.User, error) {
+	return &models.User{ID: req.Id, Username: s.user.Username}, nil
+}
+
+func (s *passkeyStorage) CreateWebAuthnChallenge(c context.Context, req *models.CreateWebAuthnChallenge) error {
+	s.challenges[req.ChallengeHash] = *req
+	return nil
+}
+
+func (s *passkeyStorage) UseWebAuthnChallenge(c context.Context, challengeHash, ceremony string) (string, error) {
+	challenge, ok := s.challenges[challengeHash]
+	if !ok || challenge.Ceremony != ceremony || !challenge.ExpiresAt.After(time.Now()) {
+		return "", errors.New("webauthn challenge is invalid or expired")
+	}
+	delete(s.challenges, challengeHash)
+	return challenge.UserId, nil
+}
+
+func (s *passkeyStorage) GetPasskeyCredentialIds(c context.Context, userId string) ([][]byte, error) {
+	return nil, nil
+}
+
+func (s *passkeyStorage) CreatePasskey(c context.Context, req *models.CreatePasskey) (string, error) {
+	if s.passkey != nil {
+		return "", storage.ErrPasskeyExists
+	}
+	s.passkey = &models.PasskeyCredential{
+		ID:        "passkey-1",
+		UserId:    req.UserId,
+		PublicKey: req.PublicKey,
+		SignCount: req.SignCount,
+	}
+	s.credential = req.CredentialId
+	return s.passkey.ID, nil
+}
+
+func (s *passkeyStorage) GetPasskeyByCredentialId(c context.Context, id []byte) (*models.PasskeyCredential, error) {
+	if s.passkey == nil || !bytes.Equal(id, s.credential) {
+		return nil, storage.ErrPasskeyNotFound
+	}
+	passkey := *s.passkey
+	return &passkey, nil
+}
+
+func (s *passkeyStorage) UsePasskey(c context.Context, req *models.UsePasskey) (*models.LoginDataRespond, error) {
+	if s.passkey == nil || s.passkey.ID != req.Id || s.passkey.SignCount != req.OldSignCount {
+		return nil, storage.ErrPasskeyNotFound
+	}
+	s.passkey.SignCount = req.SignCount
+	user := s.user
+	return &user, nil
+}
+
+func (s *passkeyStorage) CreateAuthEvent(c context.Context, req *models.CreateAuthEvent) error {
+	return nil
+}
+
+func (s *passkeyStorage) GetLoginFailure(c context.Context, key string) (*models.LoginFailure, error) {
+	return &models.LoginFailure{Key: key}, nil
+}
+
+func (s *passkeyStorage) RegisterLoginFailure(c context.Context, req *models.RegisterLoginFailure) (*models.LoginFailure, error) {
+	return &models.LoginFailure{Key: req.Key}, nil
+}
+
+type passkeyTest struct {
+	t       *testing.T
+	storage *passkeyStorage
+	router  *gin.Engine
+	device  *webauthntest.Authenticator
+}
+
+func newPasskeyTest(t *testing.T) *passkeyTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	keys, err := helper.LoadKeySet(config.Config{JWTKeyId: "test", JWTAlgorithm: helper.AlgHS256, JWTSecretKey: "test-secret"})
+	if err != nil {
+		t.Fatalf("loading keys: %v", err)
+	}
+	device, err := webauthntest.New(testRPID, testOrigin, webauthn.AlgES256)
+	if err != nil {
+		t.Fatalf("creating authenticator: %v", err)
+	}
+	// without user verification the user's 2FA is still asked for, so a
+	// successful login stops at the MFA challenge and needs no session storage
+	device.UserVerified = false
+
+	strg := newPasskeyStorage()
+	h := &Handler{
+		storage: strg,
+		log:     logger.NewLogger("test", logger.LevelFatal),
+		keys:    keys,
+		webauthn: &webauthn.RelyingParty{
+			ID:      testRPID,
+			Name:    "Example",
+			Origin:  testOrigin,
+			Timeout: time.Minute,
+		},
+	}
+
+	authenticated := func(c *gin.Context) {
+		c.Set("user_info", helper.TokenInfo{User_id: testUserId})
+	}
+
+	r := gin.New()
+	r.POST("/auth/passkey/login/begin", h.BeginPasskeyLogin)
+	r.POST("/auth/passkey/login", h.FinishPasskeyLogin)
+	r.POST("/my/passkeys/register/begin", authenticated, h.BeginPasskeyRegistration)
+	r.POST("/my/passkeys/register", authenticated, h.FinishPasskeyRegistration)
+
+	return &passkeyTest{t: t, storage: strg, router: r, device: device}
+}
+
+func (pt *passkeyTest) post(path string, body interface{}) *httptest.ResponseRecorder {
+	pt.t.Helper()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		pt.t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	pt.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+	return w
+}
+
+// begin starts a ceremony and returns the challenge handed out
+func (pt *passkeyTest) begin(path string) string {
+	pt.t.Helper()
+
+	w := pt.post(path, nil)
+	if w.Code != http.StatusOK {
+		pt.t.Fatalf("%s: status %d: %s", path, w.Code, w.Body)
+	}
+	var resp struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+		} `json:"publicKey"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		pt.t.Fatal(err)
+	}
+	return resp.PublicKey.Challenge
+}
+
+func (pt *passkeyTest) finishRegistration(challenge string) *httptest.ResponseRecorder {
+	pt.t.Helper()
+
+	credential, err := pt.device.Register(challenge, []byte(testUserId))
+	if err != nil {
+		pt.t.Fatalf("registering: %v", err)
+	}
+	return pt.post("/my/passkeys/register", models.PasskeyRegisterRequest{Name: "laptop", Credential: *credential})
+}
+
+func (pt *passkeyTest) register() {
+	pt.t.Helper()
+
+	if w := pt.finishRegistration(pt.begin("/my/passkeys/register/begin")); w.Code != http.StatusCreated {
+		pt.t.Fatalf("registration: status %d: %s", w.Code, w.Body)
+	}
+}
+
+func (pt *passkeyTest) login(challenge string) *httptest.ResponseRecorder {
+	pt.t.Helper()
+
+	assertion, err := pt.device.Assert(challenge)
+	if err != nil {
+		pt.t.Fatalf("asserting: %v", err)
+	}
+	return pt.post("/auth/passkey/login", assertion)
+}
+
+func TestPasskeyRegistration(t *testing.T) {
+	pt := newPasskeyTest(t)
+
+	if w := pt.finishRegistration(pt.begin("/my/passkeys/register/begin")); w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if pt.storage.passkey == nil || !bytes.Equal(pt.storage.credential, pt.device.CredentialID) {
+		t.Fatal("passkey was not stored")
+	}
+}
+
+func TestPasskeyRegistrationRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge func(pt *passkeyTest) string
+		before    func(pt *passkeyTest)
+	}{
+		{
+			name:      "challenge that was never handed out",
+			challenge: func(pt *passkeyTest) string { return "bm90LWhhbmRlZC1vdXQ" },
+		},
+		{
+			name:      "login challenge",
+			challenge: func(pt *passkeyTest) string { return pt.begin("/auth/passkey/login/begin") },
+		},
+		{
+			name:      "wrong origin",
+			challenge: func(pt *passkeyTest) string { return pt.begin("/my/passkeys/register/begin") },
+			before:    func(pt *passkeyTest) { pt.device.Origin = "https://evil.example" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt := newPasskeyTest(t)
+			challenge := tt.challenge(pt)
+			if tt.before != nil {
+				tt.before(pt)
+			}
+
+			if w := pt.finishRegistration(challenge); w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+			}
+			if pt.storage.passkey != nil {
+				t.Fatal("passkey was stored")
+			}
+		})
+	}
+}
+
+func TestPasskeyRegistrationReplayed(t *testing.T) {
+	pt := newPasskeyTest(t)
+
+	credential, err := pt.device.Register(pt.begin("/my/passkeys/register/begin"), []byte(testUserId))
+	if err != nil {
+		t.Fatalf("registering: %v", err)
+	}
+	body := models.PasskeyRegisterRequest{Name: "laptop", Credential: *credential}
+
+	if w := pt.post("/my/passkeys/register", body); w.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	pt.storage.passkey = nil
+	if w := pt.post("/my/passkeys/register", body); w.Code != http.StatusBadRequest {
+		t.Fatalf("replay status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+	}
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	pt := newPasskeyTest(t)
+	pt.register()
+
+	for i := 0; i < 2; i++ {
+		w := pt.login(pt.begin("/auth/passkey/login/begin"))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+	}
+	if pt.storage.passkey.SignCount != int64(pt.device.SignCount)-1 {
+		t.Fatalf("stored sign count = %d, want %d", pt.storage.passkey.SignCount, pt.device.SignCount-1)
+	}
+}
+
+func TestPasskeyLoginRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge func(pt *passkeyTest) string
+		before    func(pt *passkeyTest)
+	}{
+		{
+			name:      "challenge that was never handed out",
+			challenge: func(pt *passkeyTest) string { return "bm90LWhhbmRlZC1vdXQ" },
+		},
+		{
+			name:      "registration challenge",
+			challenge: func(pt *passkeyTest) string { return pt.begin("/my/passkeys/register/begin") },
+		},
+		{
+			name:      "wrong origin",
+			challenge: func(pt *passkeyTest) string { return pt.begin("/auth/passkey/login/begin") },
+			before:    func(pt *passkeyTest) { pt.device.Origin = "https://evil.example" },
+		},
+		{
+			name:      "sign count regression",
+			challenge: func(pt *passkeyTest) string { return pt.begin("/auth/passkey/login/begin") },
+			before: func(pt *passkeyTest) {
+				pt.storage.passkey.SignCount = 10
+				pt.device.SignCount = 4
+			},
+		},
+		{
+			name:      "sign count not moving forward",
+			challenge: func(pt *passkeyTest) string { return pt.begin("/auth/passkey/login/begin") },
+			before: func(pt *passkeyTest) {
+				pt.storage.passkey.SignCount = 10
+				pt.device.SignCount = 10
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt := newPasskeyTest(t)
+			pt.register()
+			challenge := tt.challenge(pt)
+			if tt.before != nil {
+				tt.before(pt)
+			}
+			signCount := pt.storage.passkey.SignCount
+
+			if w := pt.login(challenge); w.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusUnauthorized, w.Body)
+			}
+			if pt.storage.passkey.SignCount != signCount {
+				t.Fatalf("stored sign count moved to %d", pt.storage.passkey.SignCount)
+			}
+		})
+	}
+}
+
+func TestPasskeyLoginBadSignature(t *testing.T) {
+	pt := newPasskeyTest(t)
+	pt.register()
+
+	assertion, err := pt.device.Assert(pt.begin("/auth/passkey/login/begin"))
+	if err != nil {
+		t.Fatalf("asserting: %v", err)
+	}
+	signature, _ := webauthn.DecodeID(assertion.Response.Signature)
+	signature[len(signature)-1] ^= 0xff
+	assertion.Response.Signature = webauthn.EncodeID(signature)
+
+	if w := pt.post("/auth/passkey/login", assertion); w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusUnauthorized, w.Body)
+	}
+}
+
+func TestPasskeyLoginReplayed(t *testing.T) {
+	pt := newPasskeyTest(t)
+	pt.register()
+
+	assertion, err := pt.device.Assert(pt.begin("/auth/passkey/login/begin"))
+	if err != nil {
+		t.Fatalf("asserting: %v", err)
+	}
+
+	if w := pt.post("/auth/passkey/login", assertion); w.Code != http.StatusOK {
+		t.Fatalf("first status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if w := pt.post("/auth/passkey/login", assertion); w.Code != http.StatusUnauthorized {
+		t.Fatalf("replay status = %d, want %d: %s", w.Code, http.StatusUnauthorized, w.Body)
+	}
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/pkg/validation"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChangePassword replaces the password of the authenticated user after checking
+// the current one. Every other session is signed out.
+func (h *Handler) ChangePassword(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+
+	accountKey := loginAccountKey(userInfo.User_id)
+	if h.rejectLockedLogin(c, accountKey) {
+		return
+	}
+
+	hash, err := h.storage.User().GetPasswordHash(c.Request.Context(), userInfo.User_id)
+	if err != nil {
+		h.log.Error("error get password:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	err = helper.ComparePasswords([]byte(hash), []byte(req.CurrentPassword))
+	if err != nil {
+		if errors.Is(err, helper.ErrPasswordMismatch) {
+			h.registerLoginFailure(c, accountKey, config.LoginMaxAccountFailures)
+			h.recordAuthEvent(c, models.CreateAuthEvent{
+				UserId:    userInfo.User_id,
+				EventType: models.AuthEventPasswordChange,
+				Result:    models.AuthResultFailure,
+				Detail:    "invalid_password",
+			})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "current password didn't match"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "password comparison failed"})
+		}
+		return
+	}
+
+	var errs validation.Errors
+	h.validate.Password(&errs, "new_password", req.NewPassword, userInfo.Username)
+	if req.NewPassword == req.CurrentPassword {
+		errs.Add("new_password", validation.CodeWeak, "new password must differ from the current password")
+	}
+	if !errs.Empty() {
+		validationFailed(c, http.StatusBadRequest, errs)
+		return
+	}
+
+	hashedPass, err := helper.GeneratePasswordHash(h.cfg, req.NewPassword)
+	if err != nil {
+		h.log.Error("error while generating hash password:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	err = h.storage.User().ChangePassword(c.Request.Context(), &models.ChangePassword{
+		UserId:        userInfo.User_id,
+		Password:      string(hashedPass),
+		KeepSessionId: userInfo.SessionId,
+	})
+	if err != nil {
+		h.log.Error("error changing password:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userInfo.User_id,
+		EventType: models.AuthEventPasswordChange,
+		Result:    models.AuthResultSuccess,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "password has been changed"})
+}
+
+// upgradePasswordHash rehashes a just verified password when its stored hash
+// uses an outdated algorithm or cost. Failures are only logged.
+func (h *Handler) upgradePasswordHash(c *gin.Context, userId, hash, password string) {
+	if !helper.PasswordNeedsRehash(h.cfg, []byte(hash)) {
+		return
+	}
+
+	newHash, err := helper.GeneratePasswordHash(h.cfg, password)
+	if err != nil {
+		h.log.Error("error while generating hash password:", logger.Error(err))
+		return
+	}
+
+	err = h.storage.User().UpgradePasswordHash(c.Request.Context(), &models.UpgradePasswordHash{
+		UserId:  userId,
+		OldHash: hash,
+		NewHash: string(newHash),
+	})
+	if err != nil {
+		h.log.Error("error upgrading password hash:", logger.Error(err))
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/pkg/mailer"
+	"auth/pkg/validation"
+	"auth/storage"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForgotPassword mails a password reset link. It answers the same way whether
+// the email is registered or not, over the rate limit or not, and whether the
+// mail could be sent, so that it can't be used to find accounts. The link is
+// created and mailed in the background to keep response times alike too.
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil || !helper.IsValidEmail(req.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email"})
+		return
+	}
+
+	resp := gin.H{"message": "if the email is registered, a reset link has been sent"}
+
+	user, err := h.storage.User().GetByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			h.log.Info("password reset for unknown email:", logger.Error(err))
+		} else {
+			h.log.Error("error get by email:", logger.Error(err))
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	go h.sendPasswordReset(user)
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// sendPasswordReset creates a reset token for the user and mails the link,
+// unless the user was already sent PasswordResetMaxPerWindow links
+func (h *Handler) sendPasswordReset(user *models.LoginDataRespond) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.PasswordResetSendTimeout)
+	defer cancel()
+
+	count, err := h.storage.PasswordReset().CountPasswordResets(ctx, user.User_id, time.Now().Add(-config.PasswordResetRateWindow))
+	if err != nil {
+		h.log.Error("error counting reset tokens:", logger.Error(err))
+		return
+	}
+	if count >= config.PasswordResetMaxPerWindow {
+		h.log.Warn("password reset rate limit reached", logger.String("user_id", user.User_id))
+		return
+	}
+
+	token, err := helper.GenerateOpaqueToken(32)
+	if err != nil {
+		h.log.Error("error generating reset token:", logger.Error(err))
+		return
+	}
+
+	_, err = h.storage.PasswordReset().CreatePasswordReset(ctx, &models.CreatePasswordReset{
+		UserId:    user.User_id,
+		TokenHash: helper.HashToken(token),
+		ExpiresAt: time.Now().Add(config.PasswordResetExpireTime),
+	})
+	if err != nil {
+		h.log.Error("error creating reset token:", logger.Error(err))
+		return
+	}
+
+	err = h.mail.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to set a new password. It expires in %s.\n\n%s/reset-password?token=%s\n\nIf you didn't ask for it, ignore this email.\n",
+			user.Username, config.PasswordResetExpireTime, h.cfg.AppBaseURL, url.QueryEscape(token),
+		),
+	})
+	if err != nil {
+		h.log.Error("error sending reset mail:", logger.Error(err))
+	}
+}
+
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil || req.Token == "" {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+
+	var errs validation.Errors
+	h.validate.Password(&errs, "password", req.Password, "")
+	if !errs.Empty() {
+		validationFailed(c, http.StatusBadRequest, errs)
+		return
+	}
+
+	hashedPass, err := helper.GeneratePasswordHash(h.cfg, req.Password)
+	if err != nil {
+		h.log.Error("error while generating hash password:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	userId, err := h.storage.PasswordReset().ResetPassword(c.Request.Context(), &models.ResetPassword{
+		TokenHash: helper.HashToken(req.Token),
+		Password:  string(hashedPass),
+	})
+	if err != nil {
+		h.log.Error("error resetting password:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reset token is invalid or expired"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userId,
+		EventType: models.AuthEventPasswordReset,
+		Result:    models.AuthResultSuccess,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
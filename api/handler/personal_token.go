@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePersonalToken creates an API token for bots and integrations.
+// The token is returned only in this response, it is stored hashed.
+func (h *Handler) CreatePersonalToken(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	var req models.CreatePersonalTokenRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required and must be at most 100 characters"})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one scope is required"})
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !isKnownScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown scope " + scope, "scopes": config.Scopes})
+			return
+		}
+	}
+	if req.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days can't be negative"})
+		return
+	}
+
+	secret, err := helper.GenerateOpaqueToken(32)
+	if err != nil {
+		h.log.Error("error generating personal access token:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	token := config.PersonalTokenPrefix + secret
+
+	create := models.CreatePersonalToken{
+		UserId:    userInfo.User_id,
+		Name:      req.Name,
+		TokenHash: helper.HashToken(token),
+		Scopes:    req.Scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		create.ExpiresAt = &expiresAt
+	}
+
+	id, err := h.storage.PersonalToken().CreatePersonalToken(c.Request.Context(), &create)
+	if err != nil {
+		h.log.Error("error creating personal access token:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userInfo.User_id,
+		EventType: models.AuthEventTokenCreate,
+		Result:    models.AuthResultSuccess,
+		Detail:    id,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"message": "created", "id": id, "token": token})
+}
+
+func (h *Handler) GetMyPersonalTokens(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	resp, err := h.storage.PersonalToken().GetMyPersonalTokens(c.Request.Context(), userInfo.User_id)
+	if err != nil {
+		h.log.Error("error get personal access tokens:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) DeletePersonalToken(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	resp, err := h.storage.PersonalToken().RevokePersonalToken(c.Request.Context(), &models.RevokePersonalToken{
+		Id:     c.Param("id"),
+		UserId: userInfo.User_id,
+	})
+	if err != nil {
+		h.log.Error("error revoking personal access token:", logger.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userInfo.User_id,
+		EventType: models.AuthEventTokenRevoke,
+		Result:    models.AuthResultSuccess,
+		Detail:    resp,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "revoked token id": resp})
+}
+
+func isKnownScope(scope string) bool {
+	for _, s := range config.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/pkg/sms"
+	"auth/pkg/validation"
+	"auth/storage"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SendPhoneCode texts a one-time code for signing up or logging in with a phone
+// number. It answers the same way when no code is sent because the number is
+// already registered (sign up) or unknown (login). A code is stored either way,
+// so the resend cooldown doesn't tell the two cases apart.
+func (h *Handler) SendPhoneCode(c *gin.Context) {
+	var req models.SendPhoneCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+	req.Phone = normalizePhone(req.Phone)
+
+	var errs validation.Errors
+	h.validate.Phone(&errs, "phone", req.Phone)
+	if req.Purpose != models.PhoneCodeSignUp && req.Purpose != models.PhoneCodeLogin {
+		errs.Add("purpose", validation.CodeFormat, "purpose must be sign_up or login")
+	}
+	if !errs.Empty() {
+		validationFailed(c, http.StatusBadRequest, errs)
+		return
+	}
+
+	lastSentAt, err := h.storage.PhoneCode().LastPhoneCodeAt(c.Request.Context(), req.Phone, req.Purpose)
+	if err != nil {
+		h.log.Error("error get last phone code:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if wait := time.Until(lastSentAt.Add(config.PhoneCodeResendCooldown)); wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "a code was sent recently, try again later"})
+		return
+	}
+
+	code, err := helper.GenerateNumericCode(config.PhoneCodeLength)
+	if err != nil {
+		h.log.Error("error generating phone code:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	err = h.storage.PhoneCode().CreatePhoneCode(c.Request.Context(), &models.CreatePhoneCode{
+		Phone:     req.Phone,
+		Purpose:   req.Purpose,
+		CodeHash:  helper.HashToken(code),
+		ExpiresAt: time.Now().Add(config.PhoneCodeExpireTime),
+	})
+	if err != nil {
+		h.log.Error("error creating phone code:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	resp := gin.H{"message": "if the number can be used, a code has been sent"}
+
+	_, err = h.storage.User().GetByPhone(c.Request.Context(), req.Phone)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		h.log.Error("error get user by phone:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	registered := err == nil
+	if registered != (req.Purpose == models.PhoneCodeLogin) {
+		// the stored code is never sent, it only starts the cooldown
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	err = h.sms.Send(c.Request.Context(), sms.Message{
+		To:   req.Phone,
+		Text: fmt.Sprintf("GeedBro code: %s. It expires in %s. Don't share it with anyone.", code, config.PhoneCodeExpireTime),
+	})
+	if err != nil {
+		h.log.Error("error sending sms:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// PhoneSignUp creates a user with the phone number the code was sent to
+func (h *Handler) PhoneSignUp(c *gin.Context) {
+	var req models.PhoneSignUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+	req.Phone = normalizePhone(req.Phone)
+	req.Username = strings.TrimSpace(req.Username)
+
+	var errs validation.Errors
+	h.validate.Phone(&errs, "phone", req.Phone)
+	h.validate.Username(&errs, "username", req.Username)
+	if !errs.Empty() {
+		validationFailed(c, http.StatusBadRequest, errs)
+		return
+	}
+
+	if !h.usePhoneCode(c, req.Phone, models.PhoneCodeSignUp, req.Code) {
+		return
+	}
+
+	// the password is random and never shown, the user signs in with codes
+	password, err := helper.GenerateOpaqueToken(32)
+	if err != nil {
+		h.log.Error("error generating password:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	hashedPass, err := helper.GeneratePasswordHash(h.cfg, password)
+	if err != nil {
+		h.log.Error("error while generating hash password:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	_, err = h.storage.User().CreatePhoneUser(c.Request.Context(), &models.CreatePhoneUser{
+		Username: req.Username,
+		Phone:    req.Phone,
+		Password: string(hashedPass),
+	})
+	if err != nil {
+		if userConflict(c, err) {
+			return
+		}
+		h.log.Error("error User Create:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	user, err := h.storage.User().GetByPhone(c.Request.Context(), req.Phone)
+	if err != nil {
+		h.log.Error("error get user by phone:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.completeLogin(c, user, models.LoginMethodPhone)
+}
+
+// PhoneLogin logs in with a code sent to the user's phone
+func (h *Handler) PhoneLogin(c *gin.Context) {
+	var req models.PhoneLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+	req.Phone = normalizePhone(req.Phone)
+
+	if !h.usePhoneCode(c, req.Phone, models.PhoneCodeLogin, req.Code) {
+		return
+	}
+
+	user, err := h.storage.User().GetByPhone(c.Request.Context(), req.Phone)
+	if err != nil {
+		h.log.Error("error get user by phone:", logger.Error(err))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired code"})
+		return
+	}
+
+	h.completeLogin(c, user, models.LoginMethodPhone)
+}
+
+// usePhoneCode answers 401 and returns false unless the code is the latest
+// active one sent to the phone
+func (h *Handler) usePhoneCode(c *gin.Context, phone, purpose, code string) bool {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired code"})
+		return false
+	}
+
+	ok, err := h.storage.PhoneCode().UsePhoneCode(c.Request.Context(), &models.UsePhoneCode{
+		Phone:       phone,
+		Purpose:     purpose,
+		CodeHash:    helper.HashToken(code),
+		MaxAttempts: config.PhoneCodeMaxAttempts,
+	})
+	if err != nil {
+		h.log.Error("error using phone code:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired code"})
+		return false
+	}
+
+	return true
+}
+
+// normalizePhone drops the spaces, dashes and brackets people type in numbers
+func normalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+}
@@ -3,6 +3,8 @@ package handler
 import (
 	"auth/models"
 	"auth/pkg/logger"
+	"auth/storage"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -29,10 +31,14 @@ func (h *Handler) CreatePost(c *gin.Context) {
 }
 
 func (h *Handler) GetPost(c *gin.Context) {
-	id := c.Param("id")
+	id := c.Param("post_id")
 
 	resp, err := h.storage.Post().GetPost(c, &models.IdRequest{Id: id})
 	if err != nil {
+		if errors.Is(err, storage.ErrPostNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, err.Error())
 		fmt.Println("error Post Get:", err.Error())
 		return
@@ -87,9 +93,10 @@ func (h *Handler) GetAllPost(c *gin.Context) {
 
 	username := c.Query("search")
 	resp, err := h.storage.Post().GetAllActivePost(c.Request.Context(), &models.GetAllPostRequest{
-		Page:   &page,
-		Limit:  &limit,
-		Search: &username,
+		Page:     &page,
+		Limit:    &limit,
+		Search:   &username,
+		ViewerId: viewerId(c),
 	})
 	if err != nil {
 		h.log.Error("error:", logger.Error(err))
@@ -3,6 +3,8 @@ package handler
 import (
 	"auth/models"
 	"auth/pkg/logger"
+	"auth/storage"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -25,6 +27,14 @@ func (h *Handler) CreateComment(ctx *gin.Context) {
 
 	resp, err := h.storage.Comment().CreateComment(ctx, &comment)
 	if err != nil {
+		if errors.Is(err, storage.ErrBlocked) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, storage.ErrPostNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		fmt.Println("error comment create:", err.Error())
 		ctx.JSON(http.StatusInternalServerError, "internal server error")
 		return
@@ -66,9 +76,10 @@ func (h *Handler) GetPostComments(c *gin.Context) {
 
 	fmt.Println(post_id)
 	resp, err := h.storage.Comment().GetPostComments(c, &models.GetAllPostComments{
-		Page:   &page,
-		Limit:  &limit,
-		PostId: &post_id,
+		Page:     &page,
+		Limit:    &limit,
+		PostId:   &post_id,
+		ViewerId: viewerId(c),
 	})
 	if err != nil {
 		h.log.Error("error:", logger.Error(err))
@@ -3,6 +3,8 @@ package handler
 import (
 	"auth/models"
 	"auth/pkg/logger"
+	"auth/storage"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -20,6 +22,14 @@ func (h *Handler) CreateLike(c *gin.Context) {
 
 	err = h.storage.Like().AddLike(c, &like)
 	if err != nil {
+		if errors.Is(err, storage.ErrBlocked) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, storage.ErrPostNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		fmt.Println("error Like Create:", err.Error())
 		c.JSON(http.StatusInternalServerError, "internal server error")
 		return
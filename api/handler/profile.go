@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/pkg/validation"
+	"auth/storage"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// avatarContentTypes are the sniffed content types accepted as avatars
+var avatarContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// GetProfile shows the public profile of a user
+func (h *Handler) GetProfile(c *gin.Context) {
+	resp, err := h.storage.User().GetProfile(c.Request.Context(), c.Param("username"))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error get profile:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateMyProfile replaces the profile fields of the authenticated user. An
+// "avatar" image in the form replaces the avatar, remove_avatar=true clears it.
+func (h *Handler) UpdateMyProfile(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.AvatarMaxSize+1<<20)
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+	req.DisplayName = strings.TrimSpace(req.DisplayName)
+	req.Bio = strings.TrimSpace(req.Bio)
+	req.Website = strings.TrimSpace(req.Website)
+	req.Location = strings.TrimSpace(req.Location)
+
+	var errs validation.Errors
+	maxLength(&errs, "display_name", req.DisplayName, 50)
+	maxLength(&errs, "bio", req.Bio, 300)
+	maxLength(&errs, "location", req.Location, 100)
+	if maxLength(&errs, "website", req.Website, 255) && req.Website != "" {
+		u, err := url.Parse(req.Website)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.Add("website", validation.CodeFormat, "website must be an http or https URL")
+		}
+	}
+
+	avatar, err := c.FormFile("avatar")
+	if err != nil && !errors.Is(err, http.ErrMissingFile) {
+		h.log.Error("error reading avatar:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid avatar file"})
+		return
+	}
+	if avatar != nil {
+		if avatar.Size > config.AvatarMaxSize {
+			errs.Add("avatar", validation.CodeTooLong, fmt.Sprintf("avatar must be at most %d MB", config.AvatarMaxSize>>20))
+		} else if contentType, err := helper.DetectContentType(avatar); err != nil || !avatarContentTypes[contentType] {
+			errs.Add("avatar", validation.CodeFormat, "avatar must be a JPEG, PNG or WebP image")
+		}
+	}
+	if !errs.Empty() {
+		validationFailed(c, http.StatusBadRequest, errs)
+		return
+	}
+
+	update := models.UpdateProfile{
+		UserId:      userInfo.User_id,
+		DisplayName: req.DisplayName,
+		Bio:         req.Bio,
+		Website:     req.Website,
+		Location:    req.Location,
+		SetAvatar:   avatar != nil || req.RemoveAvatar,
+	}
+	if avatar != nil {
+		link, uploadErr := h.files.Upload(c.Request.Context(), avatar, "avatars/")
+		if uploadErr != nil {
+			h.log.Error("error uploading avatar:", logger.String("error", uploadErr.Message))
+			c.JSON(uploadErr.Code, gin.H{"error": uploadErr.Message})
+			return
+		}
+		update.AvatarURL = link
+	}
+
+	oldAvatar, err := h.storage.User().UpdateProfile(c.Request.Context(), &update)
+	if err != nil {
+		h.deleteAvatar(c, update.AvatarURL)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error updating profile:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if update.SetAvatar && oldAvatar != update.AvatarURL {
+		h.deleteAvatar(c, oldAvatar)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "avatar_url": update.AvatarURL})
+}
+
+// deleteAvatar removes an uploaded avatar file. Only files in the avatars
+// folder are touched and failures are only logged.
+func (h *Handler) deleteAvatar(c *gin.Context, link string) {
+	if !strings.HasPrefix(link, "/media/avatars/") || strings.Contains(link, "..") {
+		return
+	}
+
+	if err := h.files.Delete(c.Request.Context(), link); err != nil {
+		h.log.Error("error deleting avatar:", logger.String("error", err.Message), logger.String("avatar", link))
+	}
+}
+
+// maxLength adds a too_long error and returns false when value has more than max characters
+func maxLength(errs *validation.Errors, field, value string, max int) bool {
+	if utf8.RuneCountInString(value) > max {
+		errs.Add(field, validation.CodeTooLong, fmt.Sprintf("%s must be at most %d characters", field, max))
+		return false
+	}
+	return true
+}
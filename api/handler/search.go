@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"auth/models"
+	"auth/pkg/logger"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchQueryMaxLength is the longest user search query, as long as a display name
+const searchQueryMaxLength = 50
+
+// SearchUsers finds users by username or display name, best match first
+func (h *Handler) SearchUsers(c *gin.Context) {
+	q, ok := searchQuery(c, c.Query("q"))
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page param"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit param"})
+		return
+	}
+
+	resp, err := h.storage.User().SearchUsers(c.Request.Context(), &models.SearchUsersRequest{
+		Query:    q,
+		ViewerId: viewerId(c),
+		Page:     page,
+		Limit:    limit,
+	})
+	if err != nil {
+		h.log.Error("error searching users:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// AutocompleteUsers suggests users whose username or display name starts with
+// q while an @-mention is typed. A leading @ in q is ignored.
+func (h *Handler) AutocompleteUsers(c *gin.Context) {
+	q, ok := searchQuery(c, strings.TrimPrefix(strings.TrimSpace(c.Query("q")), "@"))
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "8"))
+	if err != nil || limit < 1 || limit > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit param"})
+		return
+	}
+
+	resp, err := h.storage.User().AutocompleteUsers(c.Request.Context(), &models.SearchUsersRequest{
+		Query:    q,
+		ViewerId: viewerId(c),
+		Limit:    limit,
+	})
+	if err != nil {
+		h.log.Error("error autocompleting users:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// searchQuery trims the search query q. It answers 400 and returns false when
+// q is empty or too long.
+func searchQuery(c *gin.Context, q string) (string, bool) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return "", false
+	}
+	if utf8.RuneCountInString(q) > searchQueryMaxLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is too long"})
+		return "", false
+	}
+
+	return q, true
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/storage"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMySessions lists the devices the user is logged in on
+func (h *Handler) GetMySessions(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	resp, err := h.storage.Session().GetMySessions(c.Request.Context(), userInfo.User_id)
+	if err != nil {
+		h.log.Error("error get sessions:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	for i := range resp.Sessions {
+		resp.Sessions[i].Current = resp.Sessions[i].ID == userInfo.SessionId
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteMySession signs out one device
+func (h *Handler) DeleteMySession(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	resp, err := h.storage.Session().RevokeSession(c.Request.Context(), &models.RevokeSession{
+		Id:     c.Param("id"),
+		UserId: userInfo.User_id,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.Error("error revoking session:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userInfo.User_id,
+		EventType: models.AuthEventSessionRevoke,
+		Result:    models.AuthResultSuccess,
+		Detail:    resp,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "revoked session id": resp})
+}
+
+// DeleteAllMySessions logs the user out everywhere. With ?except_current=true
+// the session making the request is kept.
+func (h *Handler) DeleteAllMySessions(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	req := models.RevokeAllSessions{UserId: userInfo.User_id}
+	if c.Query("except_current") == "true" {
+		req.ExceptId = userInfo.SessionId
+	}
+
+	count, err := h.storage.Session().RevokeAllSessions(c.Request.Context(), &req)
+	if err != nil {
+		h.log.Error("error revoking sessions:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userInfo.User_id,
+		EventType: models.AuthEventSessionRevoke,
+		Result:    models.AuthResultSuccess,
+		Detail:    "all",
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "revoked sessions": count})
+}
+
+// revokeSession kills a session as a side effect, e.g. when refresh token reuse
+// is detected, and only logs a failure
+func (h *Handler) revokeSession(c *gin.Context, userId, sessionId string) {
+	_, err := h.storage.Session().RevokeSession(c.Request.Context(), &models.RevokeSession{
+		Id:     sessionId,
+		UserId: userId,
+	})
+	if err != nil {
+		h.log.Error("error revoking session:", logger.Error(err), logger.String("session_id", sessionId))
+	}
+}
@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"auth/config"
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+)
+
+// EnrollTwoFactor generates a new TOTP secret for the authenticated user.
+// It isn't active until it is confirmed with a code from the authenticator app.
+func (h *Handler) EnrollTwoFactor(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	twoFactor, err := h.storage.TwoFactor().GetTwoFactor(c.Request.Context(), userInfo.User_id)
+	if err != nil {
+		h.log.Error("error get two factor:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if twoFactor.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "two factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := helper.GenerateTOTPSecret()
+	if err != nil {
+		h.log.Error("error generating totp secret:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	err = h.storage.TwoFactor().SetPendingSecret(c.Request.Context(), &models.TwoFactor{
+		UserId: userInfo.User_id,
+		Secret: secret,
+	})
+	if err != nil {
+		h.log.Error("error set totp secret:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TwoFactorEnrollRespond{
+		Secret: secret,
+		URI:    helper.TOTPURI(h.cfg.TOTPIssuer, twoFactor.Username, secret),
+	})
+}
+
+// ConfirmTwoFactor enables two factor authentication and returns the recovery codes.
+// The codes are stored hashed and are shown only this once.
+func (h *Handler) ConfirmTwoFactor(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+
+	twoFactor, err := h.storage.TwoFactor().GetTwoFactor(c.Request.Context(), userInfo.User_id)
+	if err != nil {
+		h.log.Error("error get two factor:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if twoFactor.Enabled || twoFactor.Secret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "two factor authentication is already enabled or not enrolled"})
+		return
+	}
+
+	step, ok := helper.ValidateTOTP(twoFactor.Secret, req.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+
+	codes, err := helper.GenerateRecoveryCodes(config.RecoveryCodesCount)
+	if err != nil {
+		h.log.Error("error generating recovery codes:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, helper.HashToken(code))
+	}
+
+	err = h.storage.TwoFactor().EnableTwoFactor(c.Request.Context(), &models.EnableTwoFactor{
+		UserId:     userInfo.User_id,
+		Step:       step,
+		CodeHashes: hashes,
+	})
+	if err != nil {
+		h.log.Error("error enabling two factor:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userInfo.User_id,
+		EventType: models.AuthEventTwoFactorEnable,
+		Result:    models.AuthResultSuccess,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "two factor authentication enabled", "recovery_codes": codes})
+}
+
+// DisableTwoFactor turns two factor off, asking for a current code or a recovery code
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+
+	twoFactor, err := h.storage.TwoFactor().GetTwoFactor(c.Request.Context(), userInfo.User_id)
+	if err != nil {
+		h.log.Error("error get two factor:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if !twoFactor.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "two factor authentication is not enabled"})
+		return
+	}
+
+	ok, err := h.checkTwoFactorCode(c, twoFactor, req.Code)
+	if err != nil {
+		h.log.Error("error checking two factor code:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+
+	if err = h.storage.TwoFactor().DisableTwoFactor(c.Request.Context(), userInfo.User_id); err != nil {
+		h.log.Error("error disabling two factor:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    userInfo.User_id,
+		EventType: models.AuthEventTwoFactorDisable,
+		Result:    models.AuthResultSuccess,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "two factor authentication disabled"})
+}
+
+// VerifyTwoFactor finishes a login started with a password by exchanging the
+// mfa challenge token and a code for the normal token pair
+func (h *Handler) VerifyTwoFactor(c *gin.Context) {
+	var req models.TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+
+	claims, err := helper.ExtractClaims(req.MFAToken, h.keys)
+	if err != nil || !cast.ToBool(claims["mfa_pending"]) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid mfa token"})
+		return
+	}
+	userId := cast.ToString(claims["user_id"])
+
+	accountKey := loginAccountKey(userId)
+	if h.rejectLockedLogin(c, accountKey) {
+		h.recordLoginFailure(c, userId, "account_locked")
+		return
+	}
+
+	twoFactor, err := h.storage.TwoFactor().GetTwoFactor(c.Request.Context(), userId)
+	if err != nil {
+		h.log.Error("error get two factor:", logger.Error(err))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid mfa token"})
+		return
+	}
+
+	ok, err := h.checkTwoFactorCode(c, twoFactor, req.Code)
+	if err != nil {
+		h.log.Error("error checking two factor code:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if !ok {
+		h.registerLoginFailure(c, accountKey, config.LoginMaxAccountFailures)
+		h.recordLoginFailure(c, userId, "invalid_two_factor_code")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		return
+	}
+
+	if err = h.storage.LoginFailure().ResetLoginFailures(c.Request.Context(), accountKey); err != nil {
+		h.log.Error("error resetting login failures:", logger.Error(err))
+	}
+
+	if twoFactor.DeletionScheduled {
+		if err = h.restoreAccount(c, userId); err != nil {
+			h.log.Error("error restoring account:", logger.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+	}
+
+	user, err := h.storage.User().GetUser(c.Request.Context(), &models.IdRequest{Id: userId})
+	if err != nil {
+		h.log.Error("error get user:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	tokens, err := h.issueTokens(c, helper.TokenInfo{
+		User_id:       user.ID,
+		Username:      user.Username,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		PhoneVerified: user.PhoneVerified,
+	})
+	if err != nil {
+		h.log.Error("error issuing tokens:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    user.ID,
+		EventType: models.AuthEventLogin,
+		Result:    models.AuthResultSuccess,
+		Detail:    models.LoginMethodTwoFactor,
+	})
+	h.respondTokens(c, tokens)
+}
+
+// checkTwoFactorCode accepts either a TOTP code that wasn't used before or an unused recovery code
+func (h *Handler) checkTwoFactorCode(c *gin.Context, twoFactor *models.TwoFactor, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if step, ok := helper.ValidateTOTP(twoFactor.Secret, code, time.Now()); ok {
+		return h.storage.TwoFactor().UseTOTPStep(c.Request.Context(), &models.UseTOTPStep{
+			UserId: twoFactor.UserId,
+			Step:   step,
+		})
+	}
+
+	return h.storage.TwoFactor().UseRecoveryCode(c.Request.Context(), &models.UseRecoveryCode{
+		UserId:   twoFactor.UserId,
+		CodeHash: helper.HashToken(strings.ToLower(code)),
+	})
+}
+
+// mfaChallenge returns the short lived token a client exchanges at /auth/2fa/verify.
+// It carries no role or session, so it is rejected as an access token.
+func (h *Handler) mfaChallenge(userId string) (*models.TwoFactorChallengeRespond, error) {
+	m := make(map[string]interface{})
+	m["user_id"] = userId
+	m["mfa_pending"] = true
+
+	token, err := helper.GenerateJWT(m, config.MFATokenExpireTime, h.keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorChallengeRespond{MFARequired: true, MFAToken: token}, nil
+}
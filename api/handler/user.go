@@ -1,12 +1,15 @@
 package handler
 
 import (
+	"auth/config"
 	"auth/models"
 	"auth/pkg/helper"
 	"auth/pkg/logger"
+	"auth/pkg/validation"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,7 +23,21 @@ func (h *Handler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	hashedPass, err := helper.GeneratePasswordHash(user.Password)
+	user.Username = strings.TrimSpace(user.Username)
+	user.Email = strings.TrimSpace(user.Email)
+
+	var errs validation.Errors
+	h.validate.Username(&errs, "username", user.Username)
+	if user.Email != "" {
+		h.validate.Email(&errs, "email", user.Email)
+	}
+	h.validate.Password(&errs, "password", user.Password, user.Username)
+	if !errs.Empty() {
+		validationFailed(c, http.StatusBadRequest, errs)
+		return
+	}
+
+	hashedPass, err := helper.GeneratePasswordHash(h.cfg, user.Password)
 	if err != nil {
 		h.log.Error("error while generating hash password:", logger.Error(err))
 		c.JSON(http.StatusBadRequest, "invalid body")
@@ -30,10 +47,22 @@ func (h *Handler) CreateUser(c *gin.Context) {
 
 	resp, err := h.storage.User().CreateUser(c.Request.Context(), &user)
 	if err != nil {
-		fmt.Println("error User Create:", err.Error())
-		c.JSON(http.StatusInternalServerError, "username is already used, enter another one")
+		if userConflict(c, err) {
+			return
+		}
+		h.log.Error("error User Create:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, "internal server error")
 		return
 	}
+
+	adminInfo := c.MustGet("user_info").(helper.TokenInfo)
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    resp,
+		ActorId:   adminInfo.User_id,
+		EventType: models.AuthEventSignUp,
+		Result:    models.AuthResultSuccess,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{"message": "created", "id": resp})
 }
 
@@ -85,14 +114,43 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+	user.ID = c.Param("id")
+	user.Username = strings.TrimSpace(user.Username)
+
+	var errs validation.Errors
+	h.validate.Username(&errs, "username", user.Username)
+	h.validate.Password(&errs, "password", user.Password, user.Username)
+	if !errs.Empty() {
+		validationFailed(c, http.StatusBadRequest, errs)
+		return
+	}
+
+	hashedPass, err := helper.GeneratePasswordHash(h.cfg, user.Password)
+	if err != nil {
+		h.log.Error("error while generating hash password:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	user.Password = string(hashedPass)
 
 	resp, err := h.storage.User().UpdateUser(c.Request.Context(), &user)
 	if err != nil {
+		if userConflict(c, err) {
+			return
+		}
 		h.log.Error("error User Update:", logger.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
 
+	adminInfo := c.MustGet("user_info").(helper.TokenInfo)
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    user.ID,
+		ActorId:   adminInfo.User_id,
+		EventType: models.AuthEventUserUpdate,
+		Result:    models.AuthResultSuccess,
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "success", "updated user id": resp})
 }
 
@@ -106,6 +164,19 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	_, err = h.storage.Session().RevokeAllSessions(c.Request.Context(), &models.RevokeAllSessions{UserId: id})
+	if err != nil {
+		h.log.Error("error revoking sessions:", logger.Error(err))
+	}
+
+	adminInfo := c.MustGet("user_info").(helper.TokenInfo)
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    id,
+		ActorId:   adminInfo.User_id,
+		EventType: models.AuthEventUserDelete,
+		Result:    models.AuthResultSuccess,
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "success", "deleted user id": resp})
 }
 
@@ -135,3 +206,39 @@ func (h *Handler) GetAllDeletedUser(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, resp)
 }
+
+func (h *Handler) UpdateUserRole(c *gin.Context) {
+	var req models.UpdateUserRole
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	req.ID = c.Param("id")
+
+	switch req.Role {
+	case config.RoleUser, config.RoleModerator, config.RoleAdmin:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown role"})
+		return
+	}
+
+	resp, err := h.storage.User().UpdateUserRole(c.Request.Context(), &req)
+	if err != nil {
+		h.log.Error("error User Role Update:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
+		return
+	}
+
+	adminInfo := c.MustGet("user_info").(helper.TokenInfo)
+	h.recordAuthEvent(c, models.CreateAuthEvent{
+		UserId:    req.ID,
+		ActorId:   adminInfo.User_id,
+		EventType: models.AuthEventRoleChange,
+		Result:    models.AuthResultSuccess,
+		Detail:    req.Role,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "updated user id": resp})
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"auth/pkg/validation"
+	"auth/storage"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type validationErrorResp struct {
+	Code    int               `json:"code"`
+	Message string            `json:"message"`
+	Errors  validation.Errors `json:"errors"`
+}
+
+// validationFailed answers with the list of failed field checks
+func validationFailed(c *gin.Context, status int, errs validation.Errors) {
+	c.JSON(status, validationErrorResp{
+		Code:    status,
+		Message: "validation failed",
+		Errors:  errs,
+	})
+}
+
+// userConflict answers 409 and returns true if err is a username, email or phone uniqueness error
+func userConflict(c *gin.Context, err error) bool {
+	var errs validation.Errors
+
+	switch {
+	case errors.Is(err, storage.ErrUsernameTaken):
+		errs.Add("username", validation.CodeTaken, "username is already used")
+	case errors.Is(err, storage.ErrEmailTaken):
+		errs.Add("email", validation.CodeTaken, "email is already used")
+	case errors.Is(err, storage.ErrPhoneTaken):
+		errs.Add("phone", validation.CodeTaken, "phone is already used")
+	default:
+		return false
+	}
+
+	validationFailed(c, http.StatusConflict, errs)
+	return true
+}
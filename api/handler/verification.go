@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"auth/models"
+	"auth/pkg/helper"
+	"auth/pkg/logger"
+	"auth/pkg/validation"
+	"auth/storage"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SubmitVerification sends the user's passport data for review. The data is
+// stored encrypted and only admins reviewing the request can read it.
+func (h *Handler) SubmitVerification(c *gin.Context) {
+	if !h.verificationEnabled(c) {
+		return
+	}
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	var req models.VerificationSubmitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+	req.Pinfl = strings.TrimSpace(req.Pinfl)
+	req.PassportNumber = strings.TrimSpace(req.PassportNumber)
+
+	var errs validation.Errors
+	if err := helper.ValidPinfl(req.Pinfl); err != nil {
+		errs.Add("pinfl", validation.CodeFormat, "pinfl must be 14 digits")
+	}
+	if err := helper.ValidPassportNumber(req.PassportNumber); err != nil {
+		errs.Add("passport_number", validation.CodeFormat, "passport_number must be 7 digits")
+	}
+	if !errs.Empty() {
+		validationFailed(c, http.StatusBadRequest, errs)
+		return
+	}
+
+	pinfl, err := h.cipher.Encrypt(req.Pinfl)
+	if err != nil {
+		h.log.Error("error encrypting pinfl:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	passportNumber, err := h.cipher.Encrypt(req.PassportNumber)
+	if err != nil {
+		h.log.Error("error encrypting passport number:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	id, err := h.storage.Verification().CreateVerificationRequest(c.Request.Context(), &models.CreateVerificationRequest{
+		UserId:         userInfo.User_id,
+		Pinfl:          pinfl,
+		PassportNumber: passportNumber,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrAlreadyVerified):
+			c.JSON(http.StatusConflict, gin.H{"error": "user is already verified"})
+		case errors.Is(err, storage.ErrVerificationPending):
+			c.JSON(http.StatusConflict, gin.H{"error": "a verification request is already pending"})
+		default:
+			h.log.Error("error creating verification request:", logger.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "success", "id": id, "status": models.VerificationPending})
+}
+
+// GetMyVerification shows the status of the user's latest verification request
+func (h *Handler) GetMyVerification(c *gin.Context) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+
+	resp, err := h.storage.Verification().GetMyVerificationRequest(c.Request.Context(), userInfo.User_id)
+	if err != nil {
+		if errors.Is(err, storage.ErrVerificationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no verification request"})
+			return
+		}
+		h.log.Error("error get verification request:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetAllVerificationRequests lists requests for admins with the passport data decrypted
+func (h *Handler) GetAllVerificationRequests(c *gin.Context) {
+	if !h.verificationEnabled(c) {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page param"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit param"})
+		return
+	}
+
+	status := c.DefaultQuery("status", models.VerificationPending)
+	if status != models.VerificationPending && status != models.VerificationApproved && status != models.VerificationRejected {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status param"})
+		return
+	}
+
+	resp, err := h.storage.Verification().GetAllVerificationRequests(c.Request.Context(), &models.GetAllVerificationRequest{
+		Status: status,
+		Page:   page,
+		Limit:  limit,
+	})
+	if err != nil {
+		h.log.Error("error get verification requests:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	for i := range resp.Requests {
+		request := &resp.Requests[i]
+		if request.Pinfl, err = h.cipher.Decrypt(request.Pinfl); err == nil {
+			request.PassportNumber, err = h.cipher.Decrypt(request.PassportNumber)
+		}
+		if err != nil {
+			h.log.Error("error decrypting verification request:", logger.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ApproveVerification marks the user of a pending request as verified
+func (h *Handler) ApproveVerification(c *gin.Context) {
+	h.reviewVerification(c, models.VerificationApproved, "")
+}
+
+// RejectVerification rejects a pending request with a reason shown to the user
+func (h *Handler) RejectVerification(c *gin.Context) {
+	var req models.RejectVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("error while binding:", logger.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fields in body"})
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+	if len([]rune(req.Reason)) > 300 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason must be at most 300 characters"})
+		return
+	}
+
+	h.reviewVerification(c, models.VerificationRejected, req.Reason)
+}
+
+func (h *Handler) reviewVerification(c *gin.Context, status, reason string) {
+	userInfo := c.MustGet("user_info").(helper.TokenInfo)
+	id := c.Param("id")
+
+	err := h.storage.Verification().ReviewVerificationRequest(c.Request.Context(), &models.ReviewVerification{
+		Id:         id,
+		ReviewerId: userInfo.User_id,
+		Status:     status,
+		Reason:     reason,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrVerificationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "pending verification request not found"})
+			return
+		}
+		h.log.Error("error reviewing verification request:", logger.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "success", "id": id, "status": status})
+}
+
+// verificationEnabled answers 503 and returns false when no encryption key is configured
+func (h *Handler) verificationEnabled(c *gin.Context) bool {
+	if h.cipher == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "identity verification is not available"})
+		return false
+	}
+	return true
+}
@@ -1,57 +1,166 @@
 package api
 
 import (
-	"auth/pkg/helper"
-
 	"auth/api/handler"
+	"auth/config"
+	"auth/pkg/helper"
 
 	"github.com/gin-gonic/gin"
 )
 
-func NewServer(h *handler.Handler) *gin.Engine {
+func NewServer(cfg config.Config, h *handler.Handler) (*gin.Engine, error) {
 	r := gin.Default()
 
+	// c.ClientIP() keys the login throttling and the audit log, so forwarded
+	// headers are only believed when they come from a configured proxy
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	admin := helper.RequireRole(config.RoleAdmin)
+	moderator := helper.RequireRole(config.RoleModerator, config.RoleAdmin)
+	verified := helper.RequireVerifiedContact
+
 	// authentication sign up and login
 	r.POST("/auth/login", h.Login)
 	r.POST("/auth/sign-up", h.SignUp)
+	r.POST("/auth/refresh", h.Refresh)
+	r.POST("/auth/logout", h.AuthMiddleWare, h.Logout)
+	r.GET("/.well-known/jwks.json", h.JWKS)
+	r.POST("/auth/password/forgot", h.ForgotPassword)
+	r.POST("/auth/password/reset", h.ResetPassword)
+	r.GET("/auth/verify", h.VerifyEmail)
+	r.POST("/auth/verify/resend", h.AuthMiddleWare, h.ResendVerification)
+	r.POST("/auth/2fa/verify", h.VerifyTwoFactor)
+
+	// passwordless sign in
+	r.POST("/auth/magic-link", h.SendMagicLink)
+	r.GET("/auth/magic-link/callback", h.MagicLinkCallback)
+
+	// phone number sign up and login with sms codes
+	r.POST("/auth/phone/code", h.SendPhoneCode)
+	r.POST("/auth/phone/sign-up", h.PhoneSignUp)
+	r.POST("/auth/phone/login", h.PhoneLogin)
+
+	// passkey login
+	r.POST("/auth/passkey/login/begin", h.BeginPasskeyLogin)
+	r.POST("/auth/passkey/login", h.FinishPasskeyLogin)
+
+	// sign in with external identity providers
+	r.GET("/auth/oidc/providers", h.GetOIDCProviders)
+	r.GET("/auth/oidc/:provider/login", h.OIDCLogin)
+	r.GET("/auth/oidc/:provider/callback", h.OIDCCallback)
+
+	// two factor authentication settings
+	r.POST("/my/2fa/enroll", h.AuthMiddleWare, h.EnrollTwoFactor)
+	r.POST("/my/2fa/confirm", h.AuthMiddleWare, h.ConfirmTwoFactor)
+	r.POST("/my/2fa/disable", h.AuthMiddleWare, h.DisableTwoFactor)
+
+	r.PUT("/my/password", h.AuthMiddleWare, h.ChangePassword)
+	r.DELETE("/my/account", h.AuthMiddleWare, h.DeleteMyAccount)
+
+	// passkeys
+	r.POST("/my/passkeys/register/begin", h.AuthMiddleWare, h.BeginPasskeyRegistration)
+	r.POST("/my/passkeys/register", h.AuthMiddleWare, h.FinishPasskeyRegistration)
+	r.GET("/my/passkeys", h.AuthMiddleWare, h.GetMyPasskeys)
+	r.DELETE("/my/passkeys/:id", h.AuthMiddleWare, h.DeletePasskey)
+
+	// personal data export
+	r.POST("/my/export", h.AuthMiddleWare, h.CreateExport)
+	r.GET("/my/export/:id", h.AuthMiddleWare, h.GetExport)
+
+	// authentication audit log
+	r.GET("/my/security-events", h.AuthMiddleWare, h.GetMySecurityEvents)
+	r.GET("/auth-events", h.AuthMiddleWare, admin, h.GetAuthEvents)
+
+	// identity verification
+	r.POST("/my/verification", h.AuthMiddleWare, h.SubmitVerification)
+	r.GET("/my/verification", h.AuthMiddleWare, h.GetMyVerification)
+	r.GET("/verification-requests", h.AuthMiddleWare, admin, h.GetAllVerificationRequests)
+	r.POST("/verification-requests/:id/approve", h.AuthMiddleWare, admin, h.ApproveVerification)
+	r.POST("/verification-requests/:id/reject", h.AuthMiddleWare, admin, h.RejectVerification)
+
+	// user search
+	r.GET("/users/search", h.OptionalAuthWithScope(config.ScopeUsersRead), h.SearchUsers)
+	r.GET("/users/autocomplete", h.OptionalAuthWithScope(config.ScopeUsersRead), h.AutocompleteUsers)
+
+	// profiles
+	r.GET("/users/:username", h.GetProfile)
+	r.PUT("/my/profile", h.AuthMiddleWare, h.UpdateMyProfile)
+
+	// uploaded media
+	r.Static("/media/avatars", "./media/avatars")
+
+	// followers
+	r.POST("/users/:username/follow", h.AuthMiddleWare, h.FollowUser)
+	r.DELETE("/users/:username/follow", h.AuthMiddleWare, h.UnfollowUser)
+	r.GET("/users/:username/followers", h.GetFollowers)
+	r.GET("/users/:username/following", h.GetFollowing)
+
+	// private accounts
+	r.PUT("/my/privacy", h.AuthMiddleWare, h.UpdateMyPrivacy)
+	r.GET("/my/follow-requests", h.AuthMiddleWare, h.GetMyFollowRequests)
+	r.POST("/my/follow-requests/:id/approve", h.AuthMiddleWare, h.ApproveFollowRequest)
+	r.POST("/my/follow-requests/:id/reject", h.AuthMiddleWare, h.RejectFollowRequest)
+
+	// blocked and muted users
+	r.POST("/users/:username/block", h.AuthMiddleWare, h.BlockUser)
+	r.DELETE("/users/:username/block", h.AuthMiddleWare, h.UnblockUser)
+	r.POST("/users/:username/mute", h.AuthMiddleWare, h.MuteUser)
+	r.DELETE("/users/:username/mute", h.AuthMiddleWare, h.UnmuteUser)
+	r.GET("/my/blocks", h.AuthMiddleWare, h.GetMyBlocks)
+	r.GET("/my/mutes", h.AuthMiddleWare, h.GetMyMutes)
 
 	// user routes
-	r.POST("/user", helper.AuthMiddleWare, h.CreateUser)
-	r.GET("/user/:id", helper.AuthMiddleWare, h.GetUser)
-	r.GET("/user", helper.AuthMiddleWare, h.GetAllUser)
-	r.PUT("/user/:id", helper.AuthMiddleWare, h.UpdateUser)
-	r.DELETE("/user/:id", helper.AuthMiddleWare, h.DeleteUser)
+	r.POST("/user", h.AuthMiddleWare, admin, h.CreateUser)
+	r.GET("/user/:id", h.AuthMiddleWare, admin, h.GetUser)
+	r.GET("/user", h.AuthMiddleWare, admin, h.GetAllUser)
+	r.PUT("/user/:id", h.AuthMiddleWare, admin, h.UpdateUser)
+	r.PUT("/user/:id/role", h.AuthMiddleWare, admin, h.UpdateUserRole)
+	r.POST("/user/:id/unlock", h.AuthMiddleWare, admin, h.UnlockUser)
+	r.DELETE("/user/:id", h.AuthMiddleWare, admin, h.DeleteUser)
+	r.POST("/user/:id/restore", h.AuthMiddleWare, admin, h.RestoreUser)
 
 	// delted users and posts
-	r.GET("/deleted-users", helper.AuthMiddleWare, h.GetAllDeletedUser)
-	r.GET("/deleted-posts", helper.AuthMiddleWare, h.GetAllDeletedPost)
+	r.GET("/deleted-users", h.AuthMiddleWare, admin, h.GetAllDeletedUser)
+	r.GET("/deleted-posts", h.AuthMiddleWare, moderator, h.GetAllDeletedPost)
 
 	// posts
-	r.POST("/post", helper.AuthMiddleWare, h.CreatePost)
-	r.GET("/post/:post_id", helper.AuthMiddleWare, h.GetPost)
-	r.GET("/posts/all", h.GetAllPost)
-	r.PUT("/post/:post_id", helper.AuthMiddleWare, h.UpdatePost)
-	r.DELETE("/post/:post_id", helper.AuthMiddleWare, h.DeletePost)
+	r.POST("/post", h.AuthWithScope(config.ScopePostsWrite), verified, h.CreatePost)
+	r.GET("/post/:post_id", h.AuthWithScope(config.ScopePostsRead), h.GetPost)
+	r.GET("/posts/all", h.OptionalAuthWithScope(config.ScopePostsRead), h.GetAllPost)
+	r.PUT("/post/:post_id", h.AuthWithScope(config.ScopePostsWrite), verified, h.UpdatePost)
+	r.DELETE("/post/:post_id", h.AuthWithScope(config.ScopePostsWrite), h.DeletePost)
+
+	r.GET("/my/posts", h.AuthWithScope(config.ScopePostsRead), h.GetAllMyPost)
+
+	// sessions and devices
+	r.GET("/my/sessions", h.AuthMiddleWare, h.GetMySessions)
+	r.DELETE("/my/sessions/:id", h.AuthMiddleWare, h.DeleteMySession)
+	r.DELETE("/my/sessions", h.AuthMiddleWare, h.DeleteAllMySessions)
 
-	r.GET("/my/posts", helper.AuthMiddleWare, h.GetAllMyPost)
+	// personal access tokens
+	r.POST("/my/tokens", h.AuthMiddleWare, h.CreatePersonalToken)
+	r.GET("/my/tokens", h.AuthMiddleWare, h.GetMyPersonalTokens)
+	r.DELETE("/my/tokens/:id", h.AuthMiddleWare, h.DeletePersonalToken)
 
 	// post_likes
-	r.POST("/like", helper.AuthMiddleWare, h.CreateLike)
+	r.POST("/like", h.AuthWithScope(config.ScopeLikesWrite), h.CreateLike)
 	r.GET("/like-count/:post_id", h.GetLike)
-	r.DELETE("/like", helper.AuthMiddleWare, h.DeleteLike)
+	r.DELETE("/like", h.AuthWithScope(config.ScopeLikesWrite), h.DeleteLike)
 
 	// post comment section
-	r.POST("/comment/:post_id", helper.AuthMiddleWare, h.CreateComment)
-	r.GET("/my/comments", helper.AuthMiddleWare, h.GetMyComments)
-	r.GET("/post/comment/by/post/:post_id", h.GetPostComments)
-	r.PUT("/comment", helper.AuthMiddleWare, h.UpdateComment)
-	r.DELETE("/comment/:id", helper.AuthMiddleWare, h.DeleteComment)
-	r.DELETE("/my/comment/delete/:id", helper.AuthMiddleWare, h.DeleteMyPostComment)
+	r.POST("/comment/:post_id", h.AuthWithScope(config.ScopeCommentsWrite), verified, h.CreateComment)
+	r.GET("/my/comments", h.AuthWithScope(config.ScopeCommentsRead), h.GetMyComments)
+	r.GET("/post/comment/by/post/:post_id", h.OptionalAuthWithScope(config.ScopeCommentsRead), h.GetPostComments)
+	r.PUT("/comment", h.AuthWithScope(config.ScopeCommentsWrite), verified, h.UpdateComment)
+	r.DELETE("/comment/:id", h.AuthWithScope(config.ScopeCommentsWrite), h.DeleteComment)
+	r.DELETE("/my/comment/delete/:id", h.AuthWithScope(config.ScopeCommentsWrite), h.DeleteMyPostComment)
 
 	// comment likes
-	r.POST("/comment-like", helper.AuthMiddleWare, h.CreateCommentLike)
+	r.POST("/comment-like", h.AuthWithScope(config.ScopeLikesWrite), h.CreateCommentLike)
 	r.GET("/comment-like/:comment_id", h.GetCommentLikes)
-	r.DELETE("/comment-like", helper.AuthMiddleWare, h.DeleteCommentLike)
+	r.DELETE("/comment-like", h.AuthWithScope(config.ScopeLikesWrite), h.DeleteCommentLike)
 
-	return r
+	return r, nil
 }
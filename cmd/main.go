@@ -4,7 +4,13 @@ import (
 	"auth/api"
 	"auth/api/handler"
 	"auth/config"
+	"auth/pkg/helper"
 	"auth/pkg/logger"
+	"auth/pkg/mailer"
+	"auth/pkg/oidc"
+	"auth/pkg/sms"
+	"auth/pkg/validation"
+	"auth/pkg/webauthn"
 	"auth/storage/postgres"
 	"context"
 	"fmt"
@@ -18,8 +24,47 @@ func main() {
 		return
 	}
 
-	h := handler.NewHandler(strg, log)
+	keys, err := helper.LoadKeySet(cfg)
+	if err != nil {
+		log.Fatal("error loading jwt keys:", logger.Error(err))
+	}
+
+	mail, err := mailer.NewMailer(cfg)
+	if err != nil {
+		log.Fatal("error creating mailer:", logger.Error(err))
+	}
+
+	if err = helper.CheckPasswordHashing(cfg); err != nil {
+		log.Fatal("error in password hashing config:", logger.Error(err))
+	}
+
+	validate, err := validation.New(cfg)
+	if err != nil {
+		log.Fatal("error creating validator:", logger.Error(err))
+	}
 
-	r := api.NewServer(h)
+	smsSender, err := sms.NewSender(cfg, log)
+	if err != nil {
+		log.Fatal("error creating sms sender:", logger.Error(err))
+	}
+
+	var cipher *helper.FieldCipher
+	if cfg.FieldEncryptionKey != "" {
+		cipher, err = helper.NewFieldCipher(cfg.FieldEncryptionKey)
+		if err != nil {
+			log.Fatal("error creating field cipher:", logger.Error(err))
+		}
+	} else {
+		log.Warn("FIELD_ENCRYPTION_KEY is not set, identity verification is disabled")
+	}
+
+	h := handler.NewHandler(cfg, strg, log, keys, mail, validate, oidc.NewProviders(cfg), smsSender, cipher, webauthn.NewRelyingParty(cfg), helper.NewService())
+
+	h.StartJobs(context.Background())
+
+	r, err := api.NewServer(cfg, h)
+	if err != nil {
+		log.Fatal("error creating server:", logger.Error(err))
+	}
 	r.Run(fmt.Sprintf(":%s", cfg.Port))
 }
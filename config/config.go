@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -22,16 +24,138 @@ type Config struct {
 	PostgresDatabase string
 
 	Port string
+	// TrustedProxies are the addresses or CIDRs of the reverse proxies whose
+	// X-Forwarded-For header gives the client IP. Without any the peer address is used.
+	TrustedProxies []string
 
 	PostgresMaxConnections int32
 
 	DefaultOffset int
 	DefaultLimit  int
+
+	// JWTKeysFile is a JSON file with the list of signing keys. When it is empty
+	// a single key is built from JWTAlgorithm, JWTKeyId, JWTSecretKey and JWTPrivateKeyFile.
+	JWTKeysFile       string
+	JWTAlgorithm      string
+	JWTKeyId          string
+	JWTSecretKey      string
+	JWTPrivateKeyFile string
+
+	// AuthMode is header, cookie or both. In cookie and both modes login sets
+	// HttpOnly cookies and requests authenticated by them need a CSRF token.
+	AuthMode       string
+	CookieDomain   string
+	CookieSecure   bool
+	CookieSameSite string // lax, strict, none
+
+	// OIDCProviders are the external identity providers users can sign in with.
+	OIDCProviders []OIDCProvider
+
+	// AppBaseURL is used to build links sent to users by mail.
+	AppBaseURL string
+	// TOTPIssuer is the name authenticator apps show next to the account.
+	TOTPIssuer string
+
+	// WebAuthnRPID is the domain passkeys are bound to and WebAuthnOrigin the
+	// origin the browser runs the ceremonies on. Both default to AppBaseURL.
+	WebAuthnRPID   string
+	WebAuthnRPName string
+	WebAuthnOrigin string
+
+	UsernameMinLength     int
+	UsernameMaxLength     int
+	ReservedUsernames     []string
+	PasswordMinLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+	// PasswordDenylistFile has one breached password per line.
+	PasswordDenylistFile string
+
+	// PasswordHashAlgorithm is used for new hashes. Stored hashes made with another
+	// algorithm or other parameters are upgraded on the next successful login.
+	PasswordHashAlgorithm string
+	PasswordBcryptCost    int
+
+	SMSDriver string // log, memory
+
+	// FieldEncryptionKey is a base64 encoded 32 byte key for encrypting passport
+	// data. Identity verification is disabled when it is empty.
+	FieldEncryptionKey string
+
+	// ExportDir is where personal data export archives are written, they can be
+	// downloaded for ExportExpireTime after they are ready.
+	ExportDir        string
+	ExportExpireTime time.Duration
+
+	// AccountDeletionGracePeriod is how long an account deleted by its owner can
+	// be restored by logging in before its content is purged.
+	AccountDeletionGracePeriod time.Duration
+
+	MailDriver   string // smtp, file, memory
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
 }
 
 const (
-	TokenExpireTime = 24 * time.Hour
-	JWTSecretKey    = "MySecretKey"
+	TokenExpireTime             = 15 * time.Minute
+	RefreshTokenExpireTime      = 30 * 24 * time.Hour
+	PasswordResetExpireTime     = time.Hour
+	EmailVerifyExpireTime       = 24 * time.Hour
+	MFATokenExpireTime          = 5 * time.Minute
+	OIDCStateExpireTime         = 10 * time.Minute
+	MagicLinkExpireTime         = 15 * time.Minute
+	WebAuthnChallengeExpireTime = 5 * time.Minute
+	RecoveryCodesCount          = 10
+
+	PhoneCodeExpireTime = 5 * time.Minute
+	PhoneCodeLength     = 6
+	// PhoneCodeMaxAttempts wrong guesses invalidate a code.
+	PhoneCodeMaxAttempts = 5
+	// PhoneCodeResendCooldown is the time to wait before another code is sent to the same number.
+	PhoneCodeResendCooldown = time.Minute
+
+	// MagicLinkMaxPerWindow links can be sent to one address within MagicLinkRateWindow.
+	MagicLinkMaxPerWindow = 3
+	MagicLinkRateWindow   = time.Hour
+
+	// PasswordResetMaxPerWindow reset links can be sent to one account within PasswordResetRateWindow.
+	PasswordResetMaxPerWindow = 3
+	PasswordResetRateWindow   = time.Hour
+	// PasswordResetSendTimeout is how long creating and mailing a reset link may take.
+	PasswordResetSendTimeout = time.Minute
+
+	// AvatarMaxSize is the largest avatar image that can be uploaded.
+	AvatarMaxSize = 5 << 20
+
+	// ExportBuildTimeout is how long building a data export may take, exports
+	// still pending after it are marked as failed.
+	ExportBuildTimeout = 10 * time.Minute
+	// ExportCleanupInterval is how often expired export archives are removed.
+	ExportCleanupInterval = time.Hour
+
+	// AccountPurgeInterval is how often accounts past their deletion grace period
+	// are purged, at most AccountPurgeBatchSize at a time.
+	AccountPurgeInterval  = time.Hour
+	AccountPurgeBatchSize = 100
+)
+
+// login brute-force protection
+const (
+	// LoginMaxAccountFailures failed logins lock the account for LoginLockDuration.
+	LoginMaxAccountFailures = 5
+	// LoginMaxIPFailures failed logins lock the client IP for LoginLockDuration.
+	LoginMaxIPFailures = 20
+	// LoginFailureWindow resets the failure counter when no login failed within it.
+	LoginFailureWindow = 15 * time.Minute
+	LoginLockDuration  = 15 * time.Minute
+	// LoginMaxDelay caps the progressive delay between failed attempts.
+	LoginMaxDelay = 30 * time.Second
 )
 
 const (
@@ -45,6 +169,67 @@ const (
 	TimeExpiredAt = time.Hour * 720
 )
 
+// AuthMode values select where clients get and send their tokens.
+const (
+	// AuthModeHeader accepts access tokens only in the Authorization header.
+	AuthModeHeader = "header"
+	// AuthModeCookie keeps tokens in cookies and leaves them out of response bodies.
+	AuthModeCookie = "cookie"
+	// AuthModeBoth sets cookies and also returns the tokens in response bodies.
+	AuthModeBoth = "both"
+)
+
+// personal access token scopes
+const (
+	// PersonalTokenPrefix marks personal access tokens so they can't be confused with JWTs.
+	PersonalTokenPrefix = "gbp_"
+
+	ScopePostsRead     = "posts:read"
+	ScopePostsWrite    = "posts:write"
+	ScopeCommentsRead  = "comments:read"
+	ScopeCommentsWrite = "comments:write"
+	ScopeLikesWrite    = "likes:write"
+	ScopeUsersRead     = "users:read"
+)
+
+// Scopes lists every scope a personal access token can be given.
+var Scopes = []string{
+	ScopePostsRead,
+	ScopePostsWrite,
+	ScopeCommentsRead,
+	ScopeCommentsWrite,
+	ScopeLikesWrite,
+	ScopeUsersRead,
+}
+
+const (
+	PasswordHashBcrypt   = "bcrypt"
+	PasswordHashArgon2id = "argon2id"
+
+	Argon2Time      = 1
+	Argon2Memory    = 64 * 1024
+	Argon2Threads   = 4
+	Argon2KeyLength = 32
+)
+const (
+	// RoleUser is the default role given on sign up.
+	RoleUser = "user"
+	// RoleModerator can review content of other users.
+	RoleModerator = "moderator"
+	// RoleAdmin can manage users.
+	RoleAdmin = "admin"
+)
+
+// OIDCProvider is an OpenID Connect identity provider. Its endpoints are
+// discovered from Issuer + "/.well-known/openid-configuration".
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	Scopes       []string
+}
+
 // Load ...
 func Load() Config {
 	if err := godotenv.Load("./.env"); err != nil {
@@ -55,6 +240,7 @@ func Load() Config {
 
 	config.Environment = cast.ToString(getOrReturnDefaultValue("ENVIRONMENT", DebugMode))
 	config.Port = cast.ToString(getOrReturnDefaultValue("PORT", 8000))
+	config.TrustedProxies = strings.Fields(strings.ReplaceAll(cast.ToString(getOrReturnDefaultValue("TRUSTED_PROXIES", "")), ",", " "))
 
 	config.PostgresHost = cast.ToString(getOrReturnDefaultValue("POSTGRES_HOST", "localhost"))
 	config.PostgresPort = cast.ToInt(getOrReturnDefaultValue("POSTGRES_PORT", 5432))
@@ -64,9 +250,96 @@ func Load() Config {
 
 	config.PostgresMaxConnections = cast.ToInt32(getOrReturnDefaultValue("POSTGRES_MAX_CONNECTIONS", 30))
 
+	config.JWTKeysFile = cast.ToString(getOrReturnDefaultValue("JWT_KEYS_FILE", ""))
+	config.JWTAlgorithm = cast.ToString(getOrReturnDefaultValue("JWT_ALGORITHM", "HS256"))
+	config.JWTKeyId = cast.ToString(getOrReturnDefaultValue("JWT_KEY_ID", "default"))
+	config.JWTSecretKey = cast.ToString(getOrReturnDefaultValue("JWT_SECRET_KEY", ""))
+	// a well-known secret is only acceptable for local development, elsewhere
+	// LoadKeySet refuses to start without a configured key
+	if config.JWTSecretKey == "" && config.Environment == DebugMode {
+		config.JWTSecretKey = "MySecretKey"
+	}
+	config.JWTPrivateKeyFile = cast.ToString(getOrReturnDefaultValue("JWT_PRIVATE_KEY_FILE", ""))
+
+	config.AuthMode = cast.ToString(getOrReturnDefaultValue("AUTH_MODE", AuthModeHeader))
+	config.CookieDomain = cast.ToString(getOrReturnDefaultValue("COOKIE_DOMAIN", ""))
+	config.CookieSecure = cast.ToBool(getOrReturnDefaultValue("COOKIE_SECURE", true))
+	config.CookieSameSite = cast.ToString(getOrReturnDefaultValue("COOKIE_SAME_SITE", "lax"))
+
+	config.OIDCProviders = loadOIDCProviders()
+
+	config.AppBaseURL = cast.ToString(getOrReturnDefaultValue("APP_BASE_URL", "http://localhost:8000"))
+	config.TOTPIssuer = cast.ToString(getOrReturnDefaultValue("TOTP_ISSUER", "GeedBro"))
+
+	config.WebAuthnOrigin = cast.ToString(getOrReturnDefaultValue("WEBAUTHN_ORIGIN", config.AppBaseURL))
+	config.WebAuthnRPID = cast.ToString(getOrReturnDefaultValue("WEBAUTHN_RP_ID", hostname(config.WebAuthnOrigin)))
+	config.WebAuthnRPName = cast.ToString(getOrReturnDefaultValue("WEBAUTHN_RP_NAME", "GeedBro"))
+
+	config.UsernameMinLength = cast.ToInt(getOrReturnDefaultValue("USERNAME_MIN_LENGTH", 6))
+	config.UsernameMaxLength = cast.ToInt(getOrReturnDefaultValue("USERNAME_MAX_LENGTH", 30))
+	config.ReservedUsernames = strings.Split(cast.ToString(getOrReturnDefaultValue("RESERVED_USERNAMES", "admin,administrator,root,system,support,moderator,geedbro,api,auth,me,search,autocomplete")), ",")
+	config.PasswordMinLength = cast.ToInt(getOrReturnDefaultValue("PASSWORD_MIN_LENGTH", 8))
+	config.PasswordRequireUpper = cast.ToBool(getOrReturnDefaultValue("PASSWORD_REQUIRE_UPPER", true))
+	config.PasswordRequireLower = cast.ToBool(getOrReturnDefaultValue("PASSWORD_REQUIRE_LOWER", true))
+	config.PasswordRequireDigit = cast.ToBool(getOrReturnDefaultValue("PASSWORD_REQUIRE_DIGIT", true))
+	config.PasswordRequireSymbol = cast.ToBool(getOrReturnDefaultValue("PASSWORD_REQUIRE_SYMBOL", false))
+	config.PasswordDenylistFile = cast.ToString(getOrReturnDefaultValue("PASSWORD_DENYLIST_FILE", ""))
+	config.PasswordHashAlgorithm = cast.ToString(getOrReturnDefaultValue("PASSWORD_HASH_ALGORITHM", PasswordHashBcrypt))
+	config.PasswordBcryptCost = cast.ToInt(getOrReturnDefaultValue("PASSWORD_BCRYPT_COST", 10))
+
+	config.SMSDriver = cast.ToString(getOrReturnDefaultValue("SMS_DRIVER", "log"))
+
+	config.FieldEncryptionKey = cast.ToString(getOrReturnDefaultValue("FIELD_ENCRYPTION_KEY", ""))
+
+	config.ExportDir = cast.ToString(getOrReturnDefaultValue("EXPORT_DIR", "./exports"))
+	config.ExportExpireTime = cast.ToDuration(getOrReturnDefaultValue("EXPORT_EXPIRE_TIME", "48h"))
+
+	config.AccountDeletionGracePeriod = cast.ToDuration(getOrReturnDefaultValue("ACCOUNT_DELETION_GRACE_PERIOD", "720h"))
+
+	config.MailDriver = cast.ToString(getOrReturnDefaultValue("MAIL_DRIVER", "file"))
+	config.MailFrom = cast.ToString(getOrReturnDefaultValue("MAIL_FROM", "no-reply@geedbro.uz"))
+	config.MailDir = cast.ToString(getOrReturnDefaultValue("MAIL_DIR", "./mail"))
+	config.SMTPHost = cast.ToString(getOrReturnDefaultValue("SMTP_HOST", "localhost"))
+	config.SMTPPort = cast.ToInt(getOrReturnDefaultValue("SMTP_PORT", 587))
+	config.SMTPUsername = cast.ToString(getOrReturnDefaultValue("SMTP_USERNAME", ""))
+	config.SMTPPassword = cast.ToString(getOrReturnDefaultValue("SMTP_PASSWORD", ""))
+
 	return config
 }
 
+// loadOIDCProviders reads the providers named in OIDC_PROVIDERS, each configured
+// with OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET and OIDC_<NAME>_SCOPES
+func loadOIDCProviders() []OIDCProvider {
+	var providers []OIDCProvider
+
+	for _, name := range strings.Split(cast.ToString(getOrReturnDefaultValue("OIDC_PROVIDERS", "")), ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, OIDCProvider{
+			Name:         name,
+			Issuer:       strings.TrimSuffix(cast.ToString(getOrReturnDefaultValue(prefix+"ISSUER", "")), "/"),
+			ClientId:     cast.ToString(getOrReturnDefaultValue(prefix+"CLIENT_ID", "")),
+			ClientSecret: cast.ToString(getOrReturnDefaultValue(prefix+"CLIENT_SECRET", "")),
+			Scopes:       strings.Fields(cast.ToString(getOrReturnDefaultValue(prefix+"SCOPES", "openid email profile"))),
+		})
+	}
+
+	return providers
+}
+
+// hostname returns the host of rawURL without the port
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
 func getOrReturnDefaultValue(key string, defaultValue interface{}) interface{} {
 	val, exists := os.LookupEnv(key)
 	if exists {
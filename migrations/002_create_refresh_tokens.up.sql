@@ -0,0 +1,13 @@
+CREATE TABLE "refresh_tokens" (
+  "id" varchar(36) PRIMARY KEY,
+  "family_id" varchar(36) NOT NULL,
+  "user_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "token_hash" varchar(64) NOT NULL UNIQUE,
+  "expires_at" timestamp NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT NOW(),
+  "used_at" timestamp,
+  "replaced_by" varchar(36),
+  "revoked_at" timestamp
+);
+
+CREATE INDEX "refresh_tokens_family_id_idx" ON "refresh_tokens" ("family_id");
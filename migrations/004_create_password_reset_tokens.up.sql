@@ -0,0 +1,10 @@
+ALTER TABLE "users" ADD COLUMN "email" varchar(255) UNIQUE;
+
+CREATE TABLE "password_reset_tokens" (
+  "id" varchar(36) PRIMARY KEY,
+  "user_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "token_hash" varchar(64) NOT NULL UNIQUE,
+  "expires_at" timestamp NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT NOW(),
+  "used_at" timestamp
+);
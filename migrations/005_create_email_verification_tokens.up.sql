@@ -0,0 +1,13 @@
+ALTER TABLE "users" ADD COLUMN "email_verified_at" timestamp;
+
+-- accounts created before email sign-up existed are not restricted
+UPDATE "users" SET "email_verified_at" = NOW() WHERE "email" IS NULL;
+
+CREATE TABLE "email_verification_tokens" (
+  "id" varchar(36) PRIMARY KEY,
+  "user_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "token_hash" varchar(64) NOT NULL UNIQUE,
+  "expires_at" timestamp NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT NOW(),
+  "used_at" timestamp
+);
@@ -0,0 +1,6 @@
+CREATE TABLE "login_failures" (
+  "key" varchar(100) PRIMARY KEY,
+  "failed_count" integer NOT NULL DEFAULT 0,
+  "last_failed_at" timestamp NOT NULL DEFAULT NOW(),
+  "locked_until" timestamp
+);
@@ -0,0 +1,14 @@
+ALTER TABLE "users"
+  ADD COLUMN "totp_secret" varchar(64),
+  ADD COLUMN "totp_enabled" boolean NOT NULL DEFAULT false,
+  ADD COLUMN "totp_last_step" bigint;
+
+CREATE TABLE "user_recovery_codes" (
+  "id" varchar(36) PRIMARY KEY,
+  "user_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "code_hash" varchar(64) NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT NOW(),
+  "used_at" timestamp
+);
+
+CREATE INDEX "user_recovery_codes_user_id_idx" ON "user_recovery_codes" ("user_id");
@@ -0,0 +1,26 @@
+CREATE TABLE "sessions" (
+  "id" varchar(36) PRIMARY KEY,
+  "user_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "user_agent" varchar(512) NOT NULL DEFAULT '',
+  "ip" varchar(45) NOT NULL DEFAULT '',
+  "created_at" timestamp NOT NULL DEFAULT NOW(),
+  "last_seen_at" timestamp NOT NULL DEFAULT NOW(),
+  "revoked_at" timestamp
+);
+
+CREATE INDEX "sessions_user_id_idx" ON "sessions" ("user_id");
+
+-- every refresh token family issued so far becomes a session
+INSERT INTO "sessions" ("id", "user_id", "created_at", "last_seen_at", "revoked_at")
+SELECT
+  "family_id",
+  MIN("user_id"),
+  MIN("created_at"),
+  MAX("created_at"),
+  CASE WHEN BOOL_AND("revoked_at" IS NOT NULL) THEN MAX("revoked_at") END
+FROM "refresh_tokens"
+GROUP BY "family_id";
+
+ALTER TABLE "refresh_tokens"
+  ADD CONSTRAINT "refresh_tokens_family_id_fkey"
+  FOREIGN KEY ("family_id") REFERENCES "sessions" ("id") ON DELETE CASCADE;
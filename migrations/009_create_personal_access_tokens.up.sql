@@ -0,0 +1,13 @@
+CREATE TABLE "personal_access_tokens" (
+  "id" varchar(36) PRIMARY KEY,
+  "user_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "name" varchar(100) NOT NULL,
+  "token_hash" varchar(64) NOT NULL UNIQUE,
+  "scopes" text[] NOT NULL DEFAULT '{}',
+  "expires_at" timestamp,
+  "last_used_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT NOW(),
+  "revoked_at" timestamp
+);
+
+CREATE INDEX "personal_access_tokens_user_id_idx" ON "personal_access_tokens" ("user_id");
@@ -0,0 +1,2 @@
+-- argon2id hashes are longer than the 60 characters of a bcrypt hash
+ALTER TABLE "users" ALTER COLUMN "password" TYPE varchar(255);
@@ -0,0 +1,22 @@
+CREATE TABLE "user_identities" (
+  "id" varchar(36) PRIMARY KEY,
+  "user_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "provider" varchar(50) NOT NULL,
+  "subject" varchar(255) NOT NULL,
+  "email" varchar(255),
+  "created_at" timestamp NOT NULL DEFAULT NOW(),
+  UNIQUE ("provider", "subject")
+);
+
+CREATE INDEX "user_identities_user_id_idx" ON "user_identities" ("user_id");
+
+CREATE TABLE "oidc_login_states" (
+  "id" varchar(36) PRIMARY KEY,
+  "provider" varchar(50) NOT NULL,
+  "state_hash" varchar(64) NOT NULL UNIQUE,
+  "nonce" varchar(64) NOT NULL,
+  "code_verifier" varchar(128) NOT NULL,
+  "expires_at" timestamp NOT NULL,
+  "used_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,12 @@
+CREATE TABLE "magic_link_tokens" (
+  "id" varchar(36) PRIMARY KEY,
+  "user_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "email" varchar(255) NOT NULL,
+  "token_hash" varchar(64) NOT NULL UNIQUE,
+  "device_hash" varchar(64) NOT NULL,
+  "expires_at" timestamp NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT NOW(),
+  "used_at" timestamp
+);
+
+CREATE INDEX "magic_link_tokens_email_idx" ON "magic_link_tokens" (LOWER("email"), "created_at");
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "phone_codes";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "phone_verified_at";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "phone";
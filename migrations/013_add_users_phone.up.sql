@@ -0,0 +1,15 @@
+ALTER TABLE "users" ADD COLUMN "phone" varchar(20) UNIQUE;
+ALTER TABLE "users" ADD COLUMN "phone_verified_at" timestamp;
+
+CREATE TABLE "phone_codes" (
+  "id" varchar(36) PRIMARY KEY,
+  "phone" varchar(20) NOT NULL,
+  "purpose" varchar(20) NOT NULL,
+  "code_hash" varchar(64) NOT NULL,
+  "attempts" integer NOT NULL DEFAULT 0,
+  "expires_at" timestamp NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT NOW(),
+  "used_at" timestamp
+);
+
+CREATE INDEX "phone_codes_phone_idx" ON "phone_codes" ("phone", "purpose", "created_at");
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "verification_requests";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "is_verified";
@@ -0,0 +1,16 @@
+ALTER TABLE "users" ADD COLUMN "is_verified" boolean NOT NULL DEFAULT false;
+
+CREATE TABLE "verification_requests" (
+  "id" varchar(36) PRIMARY KEY,
+  "user_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "pinfl" text NOT NULL,
+  "passport_number" text NOT NULL,
+  "status" varchar(20) NOT NULL DEFAULT 'pending',
+  "reject_reason" varchar(300),
+  "reviewed_by" varchar(36) REFERENCES "users" ("id"),
+  "reviewed_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT NOW()
+);
+
+-- a user can have only one request waiting for review
+CREATE UNIQUE INDEX "verification_requests_pending_idx" ON "verification_requests" ("user_id") WHERE "status" = 'pending';
@@ -0,0 +1,25 @@
+CREATE TABLE "auth_events" (
+  "id" varchar(36) PRIMARY KEY,
+  "user_id" varchar(36),
+  "actor_id" varchar(36),
+  "event_type" varchar(50) NOT NULL,
+  "result" varchar(20) NOT NULL,
+  "detail" varchar(100),
+  "ip" varchar(64),
+  "user_agent" varchar(512),
+  "created_at" timestamp NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX "auth_events_user_id_idx" ON "auth_events" ("user_id", "created_at");
+CREATE INDEX "auth_events_event_type_idx" ON "auth_events" ("event_type", "created_at");
+
+-- the log is append-only, rows can't be changed or removed
+CREATE FUNCTION "auth_events_append_only"() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'auth_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "auth_events_append_only"
+  BEFORE UPDATE OR DELETE ON "auth_events"
+  FOR EACH ROW EXECUTE FUNCTION "auth_events_append_only"();
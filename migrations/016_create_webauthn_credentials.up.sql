@@ -0,0 +1,22 @@
+CREATE TABLE "webauthn_credentials" (
+  "id" varchar(36) PRIMARY KEY,
+  "user_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "credential_id" bytea NOT NULL UNIQUE,
+  "public_key" bytea NOT NULL,
+  "sign_count" bigint NOT NULL DEFAULT 0,
+  "name" varchar(100) NOT NULL,
+  "last_used_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX "webauthn_credentials_user_id_idx" ON "webauthn_credentials" ("user_id");
+
+CREATE TABLE "webauthn_challenges" (
+  "id" varchar(36) PRIMARY KEY,
+  "user_id" varchar(36) REFERENCES "users" ("id") ON DELETE CASCADE,
+  "ceremony" varchar(20) NOT NULL,
+  "challenge_hash" varchar(64) NOT NULL UNIQUE,
+  "expires_at" timestamp NOT NULL,
+  "used_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+ALTER TABLE "users" ADD COLUMN "display_name" varchar(50);
+ALTER TABLE "users" ADD COLUMN "bio" varchar(300);
+ALTER TABLE "users" ADD COLUMN "website" varchar(255);
+ALTER TABLE "users" ADD COLUMN "location" varchar(100);
+ALTER TABLE "users" ADD COLUMN "avatar_url" varchar(255);
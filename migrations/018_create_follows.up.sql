@@ -0,0 +1,12 @@
+CREATE TABLE "follows" (
+  "id" varchar(36) PRIMARY KEY,
+  "follower_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "following_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "created_at" timestamp NOT NULL DEFAULT NOW(),
+  "deleted_at" timestamp,
+  CHECK ("follower_id" <> "following_id")
+);
+
+-- unfollowed rows are kept with deleted_at set, only one follow can be active
+CREATE UNIQUE INDEX "follows_active_idx" ON "follows" ("follower_id", "following_id") WHERE "deleted_at" IS NULL;
+CREATE INDEX "follows_following_id_idx" ON "follows" ("following_id") WHERE "deleted_at" IS NULL;
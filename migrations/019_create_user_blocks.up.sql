@@ -0,0 +1,20 @@
+CREATE TABLE "user_blocks" (
+  "id" varchar(36) PRIMARY KEY,
+  "blocker_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "blocked_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "created_at" timestamp NOT NULL DEFAULT NOW(),
+  UNIQUE ("blocker_id", "blocked_id"),
+  CHECK ("blocker_id" <> "blocked_id")
+);
+
+CREATE INDEX "user_blocks_blocked_id_idx" ON "user_blocks" ("blocked_id");
+
+-- a mute only hides content for the muter, the muted user isn't affected
+CREATE TABLE "user_mutes" (
+  "id" varchar(36) PRIMARY KEY,
+  "muter_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "muted_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "created_at" timestamp NOT NULL DEFAULT NOW(),
+  UNIQUE ("muter_id", "muted_id"),
+  CHECK ("muter_id" <> "muted_id")
+);
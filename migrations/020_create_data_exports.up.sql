@@ -0,0 +1,14 @@
+CREATE TABLE "data_exports" (
+  "id" varchar(36) PRIMARY KEY,
+  "user_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "status" varchar(16) NOT NULL DEFAULT 'pending',
+  "file_path" varchar(255),
+  "error" text,
+  "expires_at" timestamp,
+  "completed_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX "data_exports_user_id_idx" ON "data_exports" ("user_id");
+-- a user can have only one export being built at a time
+CREATE UNIQUE INDEX "data_exports_pending_idx" ON "data_exports" ("user_id") WHERE "status" = 'pending';
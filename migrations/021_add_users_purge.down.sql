@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "users_purge_after_idx";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "purged_at";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "purge_after";
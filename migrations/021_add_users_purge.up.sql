@@ -0,0 +1,5 @@
+-- accounts deleted by their owner are purged once purge_after has passed
+ALTER TABLE "users" ADD COLUMN "purge_after" timestamp;
+ALTER TABLE "users" ADD COLUMN "purged_at" timestamp;
+
+CREATE INDEX "users_purge_after_idx" ON "users" ("purge_after") WHERE "purged_at" IS NULL;
@@ -0,0 +1,13 @@
+ALTER TABLE "users" ADD COLUMN "is_private" boolean NOT NULL DEFAULT false;
+
+-- follows of private accounts wait here until the account owner approves them
+CREATE TABLE "follow_requests" (
+  "id" varchar(36) PRIMARY KEY,
+  "requester_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "target_id" varchar(36) NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "created_at" timestamp NOT NULL DEFAULT NOW(),
+  UNIQUE ("requester_id", "target_id"),
+  CHECK ("requester_id" <> "target_id")
+);
+
+CREATE INDEX "follow_requests_target_id_idx" ON "follow_requests" ("target_id");
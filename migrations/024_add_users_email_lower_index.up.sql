@@ -0,0 +1,6 @@
+-- emails are unique regardless of case, as GetByEmail looks them up
+CREATE UNIQUE INDEX "users_email_lower_key" ON "users" (LOWER("email"));
+
+UPDATE "users" SET "email" = LOWER("email") WHERE "email" <> LOWER("email");
+
+ALTER TABLE "users" DROP CONSTRAINT IF EXISTS "users_email_key";
@@ -0,0 +1,10 @@
+ALTER TABLE "sessions" ADD COLUMN "expires_at" timestamp;
+
+-- a session lasts as long as its newest refresh token
+UPDATE "sessions" s
+SET "expires_at" = COALESCE(
+  (SELECT MAX(rt."expires_at") FROM "refresh_tokens" rt WHERE rt."family_id" = s."id"),
+  s."last_seen_at"
+);
+
+ALTER TABLE "sessions" ALTER COLUMN "expires_at" SET NOT NULL;
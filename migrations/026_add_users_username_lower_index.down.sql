@@ -0,0 +1,3 @@
+ALTER TABLE "users" ADD CONSTRAINT "users_username_key" UNIQUE ("username");
+
+DROP INDEX IF EXISTS "users_username_lower_key";
@@ -0,0 +1,4 @@
+-- usernames are unique regardless of case, as logins and profiles look them up
+CREATE UNIQUE INDEX "users_username_lower_key" ON "users" (LOWER("username"));
+
+ALTER TABLE "users" DROP CONSTRAINT IF EXISTS "users_username_key";
@@ -1,12 +1,16 @@
 package models
 
+import "time"
+
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
 type LoginRespond struct {
-	Token string `json:"token"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	CSRFToken    string `json:"csrf_token,omitempty"`
 }
 
 type Login struct {
@@ -14,7 +18,96 @@ type Login struct {
 }
 
 type LoginDataRespond struct {
-	User_id  string `json:"user_id"`
-	Username string `json:"username"`
+	User_id          string `json:"user_id"`
+	Username         string `json:"username"`
+	Password         string `json:"-"`
+	Role             string `json:"role"`
+	Email            string `json:"email"`
+	EmailVerified    bool   `json:"email_verified"`
+	PhoneVerified    bool   `json:"phone_verified"`
+	TwoFactorEnabled bool   `json:"two_factor_enabled"`
+	// DeletionScheduled is set for accounts deleted by their owner that are
+	// still in the grace period, logging in restores them
+	DeletionScheduled bool `json:"-"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type CreateRefreshToken struct {
+	FamilyId  string
+	UserId    string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type RefreshToken struct {
+	ID            string
+	FamilyId      string
+	UserId        string
+	Username      string
+	Role          string
+	EmailVerified bool
+	PhoneVerified bool
+	ExpiresAt     time.Time
+	Used          bool
+	Revoked       bool
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
 	Password string `json:"password"`
 }
+
+type CreatePasswordReset struct {
+	UserId    string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type ResetPassword struct {
+	TokenHash string
+	Password  string
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+type CreateMagicLink struct {
+	UserId     string
+	Email      string
+	TokenHash  string
+	DeviceHash string
+	ExpiresAt  time.Time
+}
+
+type UseMagicLink struct {
+	TokenHash  string
+	DeviceHash string
+}
+
+type CreateEmailVerification struct {
+	UserId    string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type LoginFailure struct {
+	Key          string
+	FailedCount  int
+	LastFailedAt time.Time
+	LockedUntil  time.Time
+}
+
+type RegisterLoginFailure struct {
+	Key          string
+	MaxFailures  int
+	Window       time.Duration
+	LockDuration time.Duration
+}
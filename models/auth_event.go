@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// auth event types
+const (
+	AuthEventLogin            = "login"
+	AuthEventSignUp           = "sign_up"
+	AuthEventLogout           = "logout"
+	AuthEventPasswordChange   = "password_change"
+	AuthEventPasswordReset    = "password_reset"
+	AuthEventUserUpdate       = "user_update"
+	AuthEventRoleChange       = "role_change"
+	AuthEventUserDelete       = "user_delete"
+	AuthEventSessionRevoke    = "session_revoke"
+	AuthEventTokenReuse       = "refresh_token_reuse"
+	AuthEventTokenRevoke      = "personal_token_revoke"
+	AuthEventTwoFactorEnable  = "two_factor_enable"
+	AuthEventTwoFactorDisable = "two_factor_disable"
+	AuthEventTokenCreate      = "personal_token_create"
+	AuthEventPasskeyRegister  = "passkey_register"
+	AuthEventPasskeyRemove    = "passkey_remove"
+	AuthEventDataExport       = "data_export"
+	AuthEventAccountDelete    = "account_delete"
+	AuthEventAccountRestore   = "account_restore"
+)
+
+const (
+	AuthResultSuccess = "success"
+	AuthResultFailure = "failure"
+)
+
+// login methods recorded as the detail of successful logins
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodTwoFactor = "two_factor"
+	LoginMethodMagicLink = "magic_link"
+	LoginMethodPhone     = "phone"
+	LoginMethodOIDC      = "oidc"
+	LoginMethodPasskey   = "passkey"
+)
+
+// CreateAuthEvent is one entry of the audit log. UserId is the account the
+// event is about and ActorId the user who caused it when that is someone else.
+type CreateAuthEvent struct {
+	UserId    string
+	ActorId   string
+	EventType string
+	Result    string
+	Detail    string
+	IP        string
+	UserAgent string
+}
+
+type AuthEvent struct {
+	ID        string `json:"id"`
+	UserId    string `json:"user_id,omitempty"`
+	ActorId   string `json:"actor_id,omitempty"`
+	EventType string `json:"event_type"`
+	Result    string `json:"result"`
+	Detail    string `json:"detail,omitempty"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	CreatedAt string `json:"created_at"`
+}
+
+// GetAuthEventsRequest filters the audit log. Empty fields and zero times are ignored.
+type GetAuthEventsRequest struct {
+	UserId    string
+	EventType string
+	From      time.Time
+	To        time.Time
+	Page      int
+	Limit     int
+}
+
+type GetAllAuthEvents struct {
+	Events []AuthEvent `json:"events"`
+	Count  int         `json:"count"`
+}
@@ -0,0 +1,32 @@
+package models
+
+type Block struct {
+	BlockerId string
+	BlockedId string
+}
+
+type Mute struct {
+	MuterId string
+	MutedId string
+}
+
+// BlockedUser is an entry of the blocked or muted users list
+type BlockedUser struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	IsVerified  bool   `json:"is_verified"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type GetBlocksRequest struct {
+	UserId string
+	Page   int
+	Limit  int
+}
+
+type GetAllBlocks struct {
+	Users []BlockedUser `json:"users"`
+	Count int           `json:"count"`
+}
@@ -6,7 +6,8 @@ type CreateCommentLike struct {
 
 type CommentLike struct {
 	Id        string `json:"id"`
-	CommentId string `json:"post_id"`
+	CommentId string `json:"comment_id"`
+	UserId    string `json:"user_id"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 	DeletedAt string `json:"deleted_at"`
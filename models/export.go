@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+	ExportExpired = "expired"
+)
+
+type DataExport struct {
+	ID          string `json:"id"`
+	UserId      string `json:"-"`
+	Status      string `json:"status"`
+	FilePath    string `json:"-"`
+	Error       string `json:"error,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type GetExport struct {
+	Id     string
+	UserId string
+}
+
+type CompleteExport struct {
+	Id        string
+	FilePath  string
+	ExpiresAt time.Time
+}
+
+type FailExport struct {
+	Id    string
+	Error string
+}
@@ -0,0 +1,51 @@
+package models
+
+type Follow struct {
+	FollowerId  string
+	FollowingId string
+}
+
+// FollowUser is an entry of a followers or following list. IsMutual is true
+// when the two users follow each other.
+type FollowUser struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	IsVerified  bool   `json:"is_verified"`
+	IsMutual    bool   `json:"is_mutual"`
+	FollowedAt  string `json:"followed_at"`
+}
+
+type GetFollowsRequest struct {
+	UserId string
+	Page   int
+	Limit  int
+}
+
+type GetAllFollows struct {
+	Users []FollowUser `json:"users"`
+	Count int          `json:"count"`
+}
+
+// FollowRequest is a pending follow of a private account
+type FollowRequest struct {
+	ID          string `json:"id"`
+	UserId      string `json:"user_id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	IsVerified  bool   `json:"is_verified"`
+	RequestedAt string `json:"requested_at"`
+}
+
+type GetAllFollowRequests struct {
+	Requests []FollowRequest `json:"requests"`
+	Count    int             `json:"count"`
+}
+
+// ReviewFollowRequest approves or rejects request Id sent to UserId
+type ReviewFollowRequest struct {
+	Id     string
+	UserId string
+}
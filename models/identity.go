@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type CreateOIDCState struct {
+	Provider     string
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+type OIDCState struct {
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+}
+
+type LinkIdentity struct {
+	UserId   string
+	Provider string
+	Subject  string
+	Email    string
+}
+
+// CreateIdentityUser creates a user signed up through an identity provider.
+// Email is stored as verified.
+type CreateIdentityUser struct {
+	Username string
+	Email    string
+	Password string
+	Provider string
+	Subject  string
+}
+
+type OIDCProvidersRespond struct {
+	Providers []string `json:"providers"`
+}
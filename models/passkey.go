@@ -0,0 +1,68 @@
+package models
+
+import (
+	"auth/pkg/webauthn"
+	"time"
+)
+
+// webauthn ceremonies a challenge is handed out for
+const (
+	PasskeyRegistration = "registration"
+	PasskeyLogin        = "login"
+)
+
+// CreateWebAuthnChallenge stores a challenge. UserId is empty for logins,
+// the user is only known from the credential.
+type CreateWebAuthnChallenge struct {
+	UserId        string
+	Ceremony      string
+	ChallengeHash string
+	ExpiresAt     time.Time
+}
+
+// PasskeyRegisterRequest finishes a registration with the credential the browser created
+type PasskeyRegisterRequest struct {
+	Name       string                       `json:"name"`
+	Credential webauthn.AttestationResponse `json:"credential"`
+}
+
+type CreatePasskey struct {
+	UserId       string
+	Name         string
+	CredentialId []byte
+	PublicKey    []byte
+	SignCount    int64
+}
+
+type Passkey struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	LastUsedAt string `json:"last_used_at"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type GetAllPasskeys struct {
+	Passkeys []Passkey `json:"passkeys"`
+	Count    int       `json:"count"`
+}
+
+// PasskeyCredential is what a login assertion is verified against
+type PasskeyCredential struct {
+	ID        string
+	UserId    string
+	PublicKey []byte
+	SignCount int64
+}
+
+// UsePasskey moves the signature counter forward. It fails when the counter
+// changed since OldSignCount, i.e. another login used the credential meanwhile.
+type UsePasskey struct {
+	Id           string
+	OldSignCount int64
+	SignCount    int64
+}
+
+type DeletePasskey struct {
+	Id     string
+	UserId string
+}
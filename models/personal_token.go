@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+type CreatePersonalTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+type CreatePersonalToken struct {
+	UserId    string
+	Name      string
+	TokenHash string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+type PersonalToken struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expires_at"`
+	LastUsedAt string   `json:"last_used_at"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+type GetAllPersonalTokens struct {
+	Tokens []PersonalToken `json:"tokens"`
+	Count  int             `json:"count"`
+}
+
+// PersonalTokenAuth is what the auth middleware needs to know about a token and its owner
+type PersonalTokenAuth struct {
+	ID            string
+	UserId        string
+	Username      string
+	Role          string
+	EmailVerified bool
+	PhoneVerified bool
+	Scopes        []string
+	ExpiresAt     time.Time
+	Revoked       bool
+}
+
+type RevokePersonalToken struct {
+	Id     string `json:"id"`
+	UserId string `json:"user_id"`
+}
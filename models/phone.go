@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+const (
+	PhoneCodeSignUp = "sign_up"
+	PhoneCodeLogin  = "login"
+)
+
+type SendPhoneCodeRequest struct {
+	Phone   string `json:"phone"`
+	Purpose string `json:"purpose"` // sign_up or login
+}
+
+type PhoneSignUpRequest struct {
+	Phone    string `json:"phone"`
+	Code     string `json:"code"`
+	Username string `json:"username"`
+}
+
+type PhoneLoginRequest struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code"`
+}
+
+type CreatePhoneCode struct {
+	Phone     string
+	Purpose   string
+	CodeHash  string
+	ExpiresAt time.Time
+}
+
+type UsePhoneCode struct {
+	Phone       string
+	Purpose     string
+	CodeHash    string
+	MaxAttempts int
+}
+
+// CreatePhoneUser creates a user with an already verified phone number
+type CreatePhoneUser struct {
+	Username string
+	Phone    string
+	Password string
+}
@@ -6,11 +6,18 @@ type CreatePost struct {
 }
 
 type Post struct {
-	ID          string   `json:"id"`
-	Description string   `json:"description"`
-	Photos      []string `json:"photos"`
-	LikeCount   int      `json:"likes_count"`
-	CreatedAt   string   `json:"created_at"`
+	ID          string     `json:"id"`
+	Description string     `json:"description"`
+	Photos      []string   `json:"photos"`
+	LikeCount   int        `json:"likes_count"`
+	Author      PostAuthor `json:"author"`
+	CreatedAt   string     `json:"created_at"`
+}
+
+type PostAuthor struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	IsVerified bool   `json:"is_verified"`
 }
 
 type DeletePost struct {
@@ -24,9 +31,10 @@ type UpdatePost struct {
 }
 
 type GetAllPostRequest struct {
-	Page   *int    `json:"page"`
-	Limit  *int    `json:"limit"`
-	Search *string `json:"description"`
+	Page     *int    `json:"page"`
+	Limit    *int    `json:"limit"`
+	Search   *string `json:"description"`
+	ViewerId string  `json:"-"`
 }
 
 type GetAllMyPostRequest struct {
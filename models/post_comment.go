@@ -38,9 +38,10 @@ type UpdateComment struct {
 }
 
 type GetAllPostComments struct {
-	Page   *int    `json:"page"`
-	Limit  *int    `json:"limit"`
-	PostId *string `json:"post_id"`
+	Page     *int    `json:"page"`
+	Limit    *int    `json:"limit"`
+	PostId   *string `json:"post_id"`
+	ViewerId string  `json:"-"`
 }
 
 type GetAllCommentResponse struct {
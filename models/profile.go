@@ -0,0 +1,49 @@
+package models
+
+// Profile is the public view of a user
+type Profile struct {
+	ID             string `json:"id"`
+	Username       string `json:"username"`
+	DisplayName    string `json:"display_name"`
+	Bio            string `json:"bio"`
+	Website        string `json:"website"`
+	Location       string `json:"location"`
+	AvatarURL      string `json:"avatar_url"`
+	IsVerified     bool   `json:"is_verified"`
+	IsPrivate      bool   `json:"is_private"`
+	PostsCount     int    `json:"posts_count"`
+	FollowersCount int    `json:"followers_count"`
+	FollowingCount int    `json:"following_count"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// UpdateProfileRequest is sent as multipart form data, with an optional
+// "avatar" image file
+type UpdateProfileRequest struct {
+	DisplayName  string `form:"display_name"`
+	Bio          string `form:"bio"`
+	Website      string `form:"website"`
+	Location     string `form:"location"`
+	RemoveAvatar bool   `form:"remove_avatar"`
+}
+
+// UpdateProfile replaces the profile fields. The avatar is only changed when
+// SetAvatar is true, an empty AvatarURL removes it.
+type UpdateProfile struct {
+	UserId      string
+	DisplayName string
+	Bio         string
+	Website     string
+	Location    string
+	SetAvatar   bool
+	AvatarURL   string
+}
+
+type UpdatePrivacyRequest struct {
+	IsPrivate *bool `json:"is_private" binding:"required"`
+}
+
+type UpdatePrivacy struct {
+	UserId    string
+	IsPrivate bool
+}
@@ -0,0 +1,37 @@
+package models
+
+type SearchUsersRequest struct {
+	Query    string
+	ViewerId string
+	Page     int
+	Limit    int
+}
+
+// UserSearchResult is a user found by the user search, best match first
+type UserSearchResult struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	IsVerified  bool   `json:"is_verified"`
+	IsPrivate   bool   `json:"is_private"`
+	IsFollowing bool   `json:"is_following"`
+}
+
+type SearchUsers struct {
+	Users []UserSearchResult `json:"users"`
+	Count int                `json:"count"`
+}
+
+// UserSuggestion is a user suggested while typing an @-mention
+type UserSuggestion struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	IsVerified  bool   `json:"is_verified"`
+}
+
+type GetUserSuggestions struct {
+	Users []UserSuggestion `json:"users"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type CreateSession struct {
+	UserId    string
+	UserAgent string
+	IP        string
+	ExpiresAt time.Time
+}
+
+type Session struct {
+	ID         string `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	Current    bool   `json:"current"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+type GetAllSessions struct {
+	Sessions []Session `json:"sessions"`
+	Count    int       `json:"count"`
+}
+
+type RevokeSession struct {
+	Id     string `json:"id"`
+	UserId string `json:"user_id"`
+}
+
+type RevokeAllSessions struct {
+	UserId   string `json:"user_id"`
+	ExceptId string `json:"except_id"`
+}
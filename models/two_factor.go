@@ -0,0 +1,46 @@
+package models
+
+type TwoFactor struct {
+	UserId   string
+	Username string
+	Secret   string
+	Enabled  bool
+	// DeletionScheduled is set for accounts in their deletion grace period,
+	// see LoginDataRespond
+	DeletionScheduled bool
+}
+
+type EnableTwoFactor struct {
+	UserId     string
+	Step       int64
+	CodeHashes []string
+}
+
+type UseTOTPStep struct {
+	UserId string
+	Step   int64
+}
+
+type UseRecoveryCode struct {
+	UserId   string
+	CodeHash string
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorVerifyRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type TwoFactorEnrollRespond struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TwoFactorChallengeRespond struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
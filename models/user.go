@@ -1,18 +1,27 @@
 package models
 
+import "time"
+
 type CreateUser struct {
 	Username string `json:"username"`
+	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
 type User struct {
-	ID        string `json:"id"`
-	Username  string `json:"username"`
-	Password  string `json:"password"`
-	Is_active bool   `json:"is_active"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
-	DeletedAt string `json:"deleted_at"`
+	ID            string `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	PhoneVerified bool   `json:"phone_verified"`
+	Role          string `json:"role"`
+	IsVerified    bool   `json:"is_verified"`
+	Is_active     bool   `json:"is_active"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+	DeletedAt     string `json:"deleted_at"`
+	PurgeAfter    string `json:"purge_after,omitempty"`
+	PurgedAt      string `json:"purged_at,omitempty"`
 }
 
 type UpdateUser struct {
@@ -21,6 +30,49 @@ type UpdateUser struct {
 	Password string `json:"password"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ChangePassword replaces the password and revokes every session of the user
+// except KeepSessionId
+type ChangePassword struct {
+	UserId        string
+	Password      string
+	KeepSessionId string
+}
+
+// UpgradePasswordHash replaces OldHash with NewHash, unless the password was
+// changed in the meantime
+type UpgradePasswordHash struct {
+	UserId  string
+	OldHash string
+	NewHash string
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// ScheduleDeletion deactivates the account of the user until PurgeAfter, when
+// its content is removed for good
+type ScheduleDeletion struct {
+	UserId     string
+	PurgeAfter time.Time
+}
+
+// PurgedUser lists the files left behind by a purged account
+type PurgedUser struct {
+	Media       []string
+	ExportFiles []string
+}
+
+type UpdateUserRole struct {
+	ID   string `json:"id"`
+	Role string `json:"role"`
+}
+
 type IdRequest struct {
 	Id string `json:"id"`
 }
@@ -0,0 +1,53 @@
+package models
+
+const (
+	VerificationPending  = "pending"
+	VerificationApproved = "approved"
+	VerificationRejected = "rejected"
+)
+
+type VerificationSubmitRequest struct {
+	Pinfl          string `json:"pinfl"`
+	PassportNumber string `json:"passport_number"`
+}
+
+// CreateVerificationRequest holds the encrypted passport data
+type CreateVerificationRequest struct {
+	UserId         string
+	Pinfl          string
+	PassportNumber string
+}
+
+type VerificationRequest struct {
+	ID             string `json:"id"`
+	UserId         string `json:"user_id"`
+	Username       string `json:"username,omitempty"`
+	Pinfl          string `json:"pinfl,omitempty"`
+	PassportNumber string `json:"passport_number,omitempty"`
+	Status         string `json:"status"`
+	RejectReason   string `json:"reject_reason,omitempty"`
+	ReviewedAt     string `json:"reviewed_at,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+type GetAllVerificationRequest struct {
+	Status string
+	Page   int
+	Limit  int
+}
+
+type GetAllVerificationRequests struct {
+	Requests []VerificationRequest `json:"requests"`
+	Count    int                   `json:"count"`
+}
+
+type RejectVerificationRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ReviewVerification struct {
+	Id         string
+	ReviewerId string
+	Status     string
+	Reason     string
+}
@@ -16,6 +16,33 @@ import (
 	"time"
 )
 
+// uploadExtensions maps the sniffed content types accepted by Upload to the
+// extension their files are stored with
+var uploadExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// DetectContentType sniffs the content type of an uploaded file from its
+// first 512 bytes, the Content-Type sent by the client is ignored
+func DetectContentType(file *multipart.FileHeader) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return http.DetectContentType(head[:n]), nil
+}
+
 type Service struct {
 }
 
@@ -48,28 +75,25 @@ func (s Service) Upload(ctx context.Context, file *multipart.FileHeader, folder
 		i++
 	}
 
-	contentTypes := map[string]bool{
-		"application/msword": true,
-		"image/jpeg":         true,
-		"image/jpg":          true,
-		"image/png":          true,
-		"application/pdf":    true,
-		"application/vnd.openxmlformats-officedocument.wordprocessingml.document": true,
+	contentType, err := DetectContentType(file)
+	if err != nil {
+		return "", &response.ErrorResp{
+			Message: "file upload read",
+			Code:    http.StatusInternalServerError,
+		}
 	}
 
-	if len(file.Header.Values("Content-Type")) > 0 && !contentTypes[file.Header.Values("Content-Type")[0]] {
+	// the extension comes from the content, a client filename could make the
+	// file be served as html from the media folder
+	extension, ok := uploadExtensions[contentType]
+	if !ok {
 		return "", &response.ErrorResp{
 			Message: "content-type of this file has not permission to upload into the server!",
-			Code:    http.StatusInternalServerError,
+			Code:    http.StatusBadRequest,
 		}
 	}
 
-	//filename := filepath.Base(strings.Join(strings.Split(file.Filename, " "), "-"))
-
-	splitFileName := strings.Split(file.Filename, ".")
-
-	//filename := filepath.Base(strconv.Itoa(int(time.Now().UnixMicro())) + "." + splitFileName[len(splitFileName)-1])
-	filename := filepath.Base(randName + "." + splitFileName[len(splitFileName)-1])
+	filename := filepath.Base(randName + extension)
 
 	if _, err := os.Stat("./media/" + folder); errors.Is(err, os.ErrNotExist) {
 		err = os.MkdirAll("./media/"+folder, os.ModePerm)
@@ -109,7 +133,11 @@ func (s Service) Upload(ctx context.Context, file *multipart.FileHeader, folder
 			Code:    http.StatusInternalServerError,
 		}
 	}
-	defer log.Println("file upload src.Close() error: ", src.Close())
+	defer func() {
+		if err := src.Close(); err != nil {
+			log.Println("file upload src.Close() error: ", err)
+		}
+	}()
 
 	out, err := os.Create(dst)
 	if err != nil {
@@ -118,7 +146,11 @@ func (s Service) Upload(ctx context.Context, file *multipart.FileHeader, folder
 			Code:    http.StatusInternalServerError,
 		}
 	}
-	//defer log.Println("file upload out.Close() error: ", out.Close())
+	defer func() {
+		if err := out.Close(); err != nil {
+			log.Println("file upload out.Close() error: ", err)
+		}
+	}()
 
 	_, err = io.Copy(out, src)
 
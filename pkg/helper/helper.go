@@ -3,17 +3,8 @@ package helper
 import (
 	"strconv"
 	"strings"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
-func GeneratePasswordHash(pass string) ([]byte, error) {
-	return bcrypt.GenerateFromPassword([]byte(pass), 10)
-}
-func ComparePasswords(hashedPass, pass []byte) error {
-	return bcrypt.CompareHashAndPassword(hashedPass, pass)
-}
-
 func ReplaceQueryParams(namedQuery string, params map[string]interface{}) (string, []interface{}) {
 	var (
 		i    int = 1
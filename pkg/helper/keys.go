@@ -0,0 +1,288 @@
+package helper
+
+import (
+	"auth/config"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// SigningKey is one key of the key set, identified by the "kid" token header
+type SigningKey struct {
+	Id        string
+	Algorithm string
+	// Private is used for signing: []byte for HS256, *rsa.PrivateKey or ed25519.PrivateKey otherwise.
+	// It is nil for verify-only keys.
+	Private interface{}
+	// Public is used for verification: []byte for HS256, *rsa.PublicKey or ed25519.PublicKey otherwise
+	Public interface{}
+}
+
+// KeySet holds the key used to sign new tokens and every key that is still accepted
+// for verification, so that keys can be rotated without invalidating issued tokens.
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+type keyFileEntry struct {
+	Id             string `json:"kid"`
+	Algorithm      string `json:"alg"`
+	Secret         string `json:"secret"`
+	PrivateKeyFile string `json:"private_key_file"`
+	PublicKeyFile  string `json:"public_key_file"`
+	Active         bool   `json:"active"`
+}
+
+// LoadKeySet builds the key set from config. When JWTKeysFile is set it is read as a
+// JSON array of keys, otherwise a single key is built from the JWT_* environment values.
+func LoadKeySet(cfg config.Config) (*KeySet, error) {
+	var entries []keyFileEntry
+
+	if cfg.JWTKeysFile != "" {
+		data, err := os.ReadFile(cfg.JWTKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jwt keys file: %w", err)
+		}
+		if err = json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse jwt keys file: %w", err)
+		}
+	} else {
+		entries = append(entries, keyFileEntry{
+			Id:             cfg.JWTKeyId,
+			Algorithm:      cfg.JWTAlgorithm,
+			Secret:         cfg.JWTSecretKey,
+			PrivateKeyFile: cfg.JWTPrivateKeyFile,
+			Active:         true,
+		})
+	}
+
+	set := &KeySet{keys: make(map[string]*SigningKey)}
+	for _, entry := range entries {
+		key, err := newSigningKey(entry)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", entry.Id, err)
+		}
+		if _, exists := set.keys[key.Id]; exists {
+			return nil, fmt.Errorf("jwt key %q is duplicated", key.Id)
+		}
+		set.keys[key.Id] = key
+
+		if entry.Active {
+			if set.active != nil {
+				return nil, errors.New("only one jwt key can be active")
+			}
+			if key.Private == nil {
+				return nil, fmt.Errorf("active jwt key %q has no private key", key.Id)
+			}
+			set.active = key
+		}
+	}
+
+	if set.active == nil {
+		return nil, errors.New("no active jwt key")
+	}
+
+	return set, nil
+}
+
+func newSigningKey(entry keyFileEntry) (*SigningKey, error) {
+	if entry.Id == "" {
+		return nil, errors.New("kid is required")
+	}
+
+	key := &SigningKey{Id: entry.Id, Algorithm: entry.Algorithm}
+
+	switch entry.Algorithm {
+	case AlgHS256:
+		if entry.Secret == "" {
+			return nil, errors.New("secret is required for HS256")
+		}
+		key.Private = []byte(entry.Secret)
+		key.Public = []byte(entry.Secret)
+	case AlgRS256, AlgEdDSA:
+		if entry.PrivateKeyFile != "" {
+			private, err := readPrivateKey(entry.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			signer, ok := private.(crypto.Signer)
+			if !ok {
+				return nil, errors.New("private key can't sign")
+			}
+			key.Private = private
+			key.Public = signer.Public()
+		} else if entry.PublicKeyFile != "" {
+			public, err := readPublicKey(entry.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			key.Public = public
+		} else {
+			return nil, errors.New("private_key_file or public_key_file is required")
+		}
+
+		switch key.Public.(type) {
+		case *rsa.PublicKey:
+			if entry.Algorithm != AlgRS256 {
+				return nil, errors.New("rsa key can only be used with RS256")
+			}
+		case ed25519.PublicKey:
+			if entry.Algorithm != AlgEdDSA {
+				return nil, errors.New("ed25519 key can only be used with EdDSA")
+			}
+		default:
+			return nil, errors.New("unsupported key type")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", entry.Algorithm)
+	}
+
+	return key, nil
+}
+
+func readPrivateKey(path string) (interface{}, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	return x509.ParsePKCS8PrivateKey(block.Bytes)
+}
+
+func readPublicKey(path string) (interface{}, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	return block, nil
+}
+
+// Active returns the key new tokens are signed with
+func (s *KeySet) Active() *SigningKey {
+	return s.active
+}
+
+// Lookup returns the verification key for the given kid
+func (s *KeySet) Lookup(kid string) (*SigningKey, bool) {
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS returns the public keys of the set. Symmetric keys are never published.
+func (s *KeySet) JWKS() []JWK {
+	keys := make([]JWK, 0, len(s.keys))
+
+	for _, key := range s.keys {
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "RSA",
+				Kid: key.Id,
+				Alg: key.Algorithm,
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "OKP",
+				Kid: key.Id,
+				Alg: key.Algorithm,
+				Use: "sig",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+
+	return keys
+}
+
+// SigningMethodEdDSA implements the EdDSA (Ed25519) signing method, which
+// github.com/dgrijalva/jwt-go does not provide
+type SigningMethodEdDSA struct{}
+
+func init() {
+	jwt.RegisterSigningMethod(AlgEdDSA, func() jwt.SigningMethod {
+		return &SigningMethodEdDSA{}
+	})
+}
+
+func (m *SigningMethodEdDSA) Alg() string {
+	return AlgEdDSA
+}
+
+func (m *SigningMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(public, []byte(signingString), sig) {
+		return errors.New("ed25519: verification error")
+	}
+	return nil
+}
+
+func (m *SigningMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	return jwt.EncodeSegment(ed25519.Sign(private, []byte(signingString))), nil
+}
@@ -1,7 +1,6 @@
 package helper
 
 import (
-	"auth/config"
 	"fmt"
 	"net/http"
 	"time"
@@ -58,30 +57,50 @@ func afterRequest(c *gin.Context) {
 	fmt.Printf("Completed %s %s in %v\n", c.Request.Method, c.Request.URL.Path, duration)
 }
 
-// AuthMiddleWare is a middleware function for authentication
-func AuthMiddleWare(c *gin.Context) {
-	token := c.GetHeader("Authorization")
-	// Request.Header
+// RequireRole allows the request only if the authenticated user has one of the given roles.
+// It must be placed after the auth middleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
 
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, map[string]interface{}{
-			"code":    "UNAUTHORIZED!",
-			"message": "Token not found...",
-		})
-		c.Abort()
-		return
+	return func(c *gin.Context) {
+		userInfo, ok := c.Get("user_info")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"code":    "UNAUTHORIZED!",
+				"message": "Token not found...",
+			})
+			c.Abort()
+			return
+		}
+
+		if !allowed[userInfo.(TokenInfo).Role] {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"code":    "FORBIDDEN!",
+				"message": "You don't have permission to access this resource...",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
 	}
+}
 
-	userInfo, err := ParseClaims(token, config.JWTSecretKey)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, map[string]interface{}{
-			"code":    "INVALID TOKEN!",
-			"message": "Provided token is not valid...",
+// RequireVerifiedContact allows the request only if the authenticated user has confirmed their
+// email or phone number. It must be placed after the auth middleware.
+func RequireVerifiedContact(c *gin.Context) {
+	userInfo, ok := c.Get("user_info")
+	if !ok || !(userInfo.(TokenInfo).EmailVerified || userInfo.(TokenInfo).PhoneVerified) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"code":    "EMAIL NOT VERIFIED!",
+			"message": "Please verify your email or phone number first...",
 		})
 		c.Abort()
 		return
 	}
 
-	c.Set("user_info", userInfo)
 	c.Next()
 }
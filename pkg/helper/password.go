@@ -0,0 +1,141 @@
+package helper
+
+import (
+	"auth/config"
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrPasswordMismatch is returned by ComparePasswords when the password is wrong
+var ErrPasswordMismatch = bcrypt.ErrMismatchedHashAndPassword
+
+var argon2idPrefix = []byte("$argon2id$")
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+// CheckPasswordHashing validates the configured password hash algorithm and cost
+func CheckPasswordHashing(cfg config.Config) error {
+	switch cfg.PasswordHashAlgorithm {
+	case config.PasswordHashArgon2id:
+		return nil
+	case config.PasswordHashBcrypt:
+		if cfg.PasswordBcryptCost < bcrypt.MinCost || cfg.PasswordBcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown password hash algorithm %q", cfg.PasswordHashAlgorithm)
+}
+
+// GeneratePasswordHash hashes the password with cfg.PasswordHashAlgorithm
+func GeneratePasswordHash(cfg config.Config, pass string) ([]byte, error) {
+	if cfg.PasswordHashAlgorithm == config.PasswordHashArgon2id {
+		return generateArgon2idHash(pass)
+	}
+	return bcrypt.GenerateFromPassword([]byte(pass), cfg.PasswordBcryptCost)
+}
+
+// ComparePasswords checks the password against a bcrypt or argon2id hash
+func ComparePasswords(hashedPass, pass []byte) error {
+	if bytes.HasPrefix(hashedPass, argon2idPrefix) {
+		return compareArgon2idHash(hashedPass, pass)
+	}
+	return bcrypt.CompareHashAndPassword(hashedPass, pass)
+}
+
+// PasswordNeedsRehash reports whether the hash was made with another algorithm
+// or other parameters than GeneratePasswordHash currently uses
+func PasswordNeedsRehash(cfg config.Config, hashedPass []byte) bool {
+	if bytes.HasPrefix(hashedPass, argon2idPrefix) {
+		if cfg.PasswordHashAlgorithm != config.PasswordHashArgon2id {
+			return true
+		}
+		params, _, _, err := decodeArgon2idHash(hashedPass)
+		return err != nil || params != currentArgon2Params()
+	}
+
+	if cfg.PasswordHashAlgorithm != config.PasswordHashBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost(hashedPass)
+	return err != nil || cost != cfg.PasswordBcryptCost
+}
+
+func currentArgon2Params() argon2Params {
+	return argon2Params{
+		time:    config.Argon2Time,
+		memory:  config.Argon2Memory,
+		threads: config.Argon2Threads,
+	}
+}
+
+// generateArgon2idHash encodes the hash in the PHC string format:
+// $argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>
+func generateArgon2idHash(pass string) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	p := currentArgon2Params()
+	key := argon2.IDKey([]byte(pass), salt, p.time, p.memory, p.threads, config.Argon2KeyLength)
+
+	return []byte(fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)), nil
+}
+
+func compareArgon2idHash(hashedPass, pass []byte) error {
+	p, salt, key, err := decodeArgon2idHash(hashedPass)
+	if err != nil {
+		return err
+	}
+
+	other := argon2.IDKey(pass, salt, p.time, p.memory, p.threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+func decodeArgon2idHash(hashedPass []byte) (p argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(string(hashedPass), "$")
+	if len(parts) != 6 {
+		return p, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+	if version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+
+	return p, salt, key, nil
+}
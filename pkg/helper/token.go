@@ -1,7 +1,13 @@
 package helper
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"math/big"
 	"strings"
 	"time"
 
@@ -10,14 +16,37 @@ import (
 )
 
 type TokenInfo struct {
-	Username string `json:"username"`
-	User_id  string `json:"user_id"`
+	Username      string `json:"username"`
+	User_id       string `json:"user_id"`
+	Role          string `json:"role"`
+	EmailVerified bool   `json:"email_verified"`
+	PhoneVerified bool   `json:"phone_verified"`
+	SessionId     string `json:"sid"`
+
+	// TokenId and Scopes are set only when a personal access token was used
+	TokenId string   `json:"token_id"`
+	Scopes  []string `json:"scopes"`
+}
+
+// HasScope reports whether the request may use the scope. JWT sessions have every scope.
+func (t TokenInfo) HasScope(scope string) bool {
+	if t.TokenId == "" {
+		return true
+	}
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
 
 // GenerateJWT ...
-func GenerateJWT(m map[string]interface{}, tokenExpireTime time.Duration, tokenSecretKey string) (tokenString string, err error) {
+func GenerateJWT(m map[string]interface{}, tokenExpireTime time.Duration, keys *KeySet) (tokenString string, err error) {
+	key := keys.Active()
 
-	var token = jwt.New(jwt.SigningMethodHS256)
+	var token = jwt.New(jwt.GetSigningMethod(key.Algorithm))
+	token.Header["kid"] = key.Id
 
 	claims := token.Claims.(jwt.MapClaims)
 
@@ -28,7 +57,7 @@ func GenerateJWT(m map[string]interface{}, tokenExpireTime time.Duration, tokenS
 	claims["iat"] = time.Now().Unix()
 	claims["exp"] = time.Now().Add(tokenExpireTime).Unix()
 
-	tokenString, err = token.SignedString([]byte(tokenSecretKey))
+	tokenString, err = token.SignedString(key.Private)
 	if err != nil {
 		return "", err
 	}
@@ -36,10 +65,10 @@ func GenerateJWT(m map[string]interface{}, tokenExpireTime time.Duration, tokenS
 	return tokenString, nil
 }
 
-func ParseClaims(token string, secretKey string) (result TokenInfo, err error) {
+func ParseClaims(token string, keys *KeySet) (result TokenInfo, err error) {
 	var claims jwt.MapClaims
 
-	claims, err = ExtractClaims(token, secretKey)
+	claims, err = ExtractClaims(token, keys)
 	if err != nil {
 		return result, err
 	}
@@ -51,24 +80,47 @@ func ParseClaims(token string, secretKey string) (result TokenInfo, err error) {
 	}
 
 	result.User_id = cast.ToString(claims["user_id"])
-	if len(result.Username) <= 0 {
+	if len(result.User_id) <= 0 {
 		err = errors.New("cannot parse 'user_id' field")
 		return result, err
 	}
 
+	result.Role = cast.ToString(claims["role"])
+	if len(result.Role) <= 0 {
+		err = errors.New("cannot parse 'role' field")
+		return result, err
+	}
+
+	result.EmailVerified = cast.ToBool(claims["email_verified"])
+	result.PhoneVerified = cast.ToBool(claims["phone_verified"])
+
+	result.SessionId = cast.ToString(claims["sid"])
+	if len(result.SessionId) <= 0 {
+		err = errors.New("cannot parse 'sid' field")
+		return result, err
+	}
+
 	return
 }
 
-// ExtractClaims extracts claims from given token
-func ExtractClaims(tokenString string, tokenSecretKey string) (jwt.MapClaims, error) {
+// ExtractClaims extracts claims from given token. The token must name a known key
+// in its "kid" header and be signed with exactly that key's algorithm.
+func ExtractClaims(tokenString string, keys *KeySet) (jwt.MapClaims, error) {
 	var (
 		token *jwt.Token
 		err   error
 	)
 
 	token, err = jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// check token signing method etc
-		return []byte(tokenSecretKey), nil
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+		}
+		return key.Public, nil
 	})
 
 	if err != nil {
@@ -83,11 +135,36 @@ func ExtractClaims(tokenString string, tokenSecretKey string) (jwt.MapClaims, er
 	return claims, nil
 }
 
-// ExtractToken checks and returns token part of input string
+// ExtractToken checks and returns token part of a "Bearer <token>" header value
 func ExtractToken(bearer string) (token string, err error) {
 	strArr := strings.Split(bearer, " ")
-	if len(strArr) == 2 {
+	if len(strArr) == 2 && strings.EqualFold(strArr[0], "Bearer") {
 		return strArr[1], nil
 	}
 	return token, errors.New("wrong token format")
 }
+
+// GenerateNumericCode returns a random code of n decimal digits
+func GenerateNumericCode(n int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+	v, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", n, v), nil
+}
+
+// GenerateOpaqueToken returns a url-safe random token with n bytes of entropy
+func GenerateOpaqueToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex encoded sha256 of an opaque token, used to store tokens at rest
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
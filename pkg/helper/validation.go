@@ -3,7 +3,6 @@ package helper
 import (
 	"errors"
 	"regexp"
-	"unicode"
 )
 
 func ValidPinfl(pinfl string) error {
@@ -42,12 +41,6 @@ func IsValidEmail(email string) bool {
 	return r.MatchString(email)
 }
 
-// IsValidLogin ...
-func IsValidLogin(login string) bool {
-	r := regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{5,29}$`)
-	return r.MatchString(login)
-}
-
 // IsValidUUID ...
 func IsValidUUID(uuid string) bool {
 	r := regexp.MustCompile("^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}$")
@@ -64,50 +57,3 @@ func IsValidPrice(price string) bool {
 	r := regexp.MustCompile(`^\d+$`)
 	return r.MatchString(price)
 }
-
-// IsValidPassword ...
-// The password must be at least 8 characters long.
-// The password must contain at least one uppercase letter.
-// The password must contain at least one lowercase letter.
-// The password must contain at least one digit.
-func IsValidPassword(password string) bool {
-	// Password must be at least 8 characters long
-	if len(password) < 8 {
-		return false
-	}
-
-	// Password must contain at least one uppercase letter
-	hasUppercase := false
-	for _, char := range password {
-		if unicode.IsUpper(char) {
-			hasUppercase = true
-			break
-		}
-	}
-	if !hasUppercase {
-		return false
-	}
-
-	// Password must contain at least one lowercase letter
-	hasLowercase := false
-	for _, char := range password {
-		if unicode.IsLower(char) {
-			hasLowercase = true
-			break
-		}
-	}
-	if !hasLowercase {
-		return false
-	}
-
-	// Password must contain at least one digit
-	hasDigit := false
-	for _, char := range password {
-		if unicode.IsDigit(char) {
-			hasDigit = true
-			break
-		}
-	}
-
-	return hasDigit
-}
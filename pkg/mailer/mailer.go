@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"auth/config"
+	"context"
+	"fmt"
+)
+
+const (
+	// DriverSMTP sends mail through an SMTP server.
+	DriverSMTP = "smtp"
+	// DriverFile writes every mail as a file into a directory.
+	DriverFile = "file"
+	// DriverMemory keeps mail in memory, for tests.
+	DriverMemory = "memory"
+)
+
+// Message ...
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// MailerI ...
+type MailerI interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer returns the mailer selected by cfg.MailDriver
+func NewMailer(cfg config.Config) (MailerI, error) {
+	switch cfg.MailDriver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	case DriverFile:
+		return NewFileMailer(cfg.MailDir, cfg.MailFrom), nil
+	case DriverMemory:
+		return NewMemoryMailer(), nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", cfg.MailDriver)
+}
@@ -0,0 +1,273 @@
+package oidc_test
+
+import (
+	"auth/config"
+	"auth/pkg/oidc"
+	"auth/pkg/oidc/oidctest"
+	"context"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const (
+	testClientId     = "client-1"
+	testClientSecret = "s3cret/+="
+	testRedirectURL  = "https://app.example/auth/oidc/mock/callback"
+)
+
+func newIssuer(t *testing.T) *oidctest.Issuer {
+	t.Helper()
+
+	issuer, err := oidctest.NewIssuer(testClientId, testClientSecret)
+	if err != nil {
+		t.Fatalf("starting issuer: %v", err)
+	}
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+func newProvider(issuer *oidctest.Issuer) *oidc.Provider {
+	return oidc.NewProvider(config.OIDCProvider{
+		Name:         "mock",
+		Issuer:       issuer.URL,
+		ClientId:     testClientId,
+		ClientSecret: testClientSecret,
+		Scopes:       []string{"openid", "email", "profile"},
+	}, testRedirectURL)
+}
+
+// flow is one login: the values the callback handler keeps between the
+// redirect to the provider and the exchange
+type flow struct {
+	verifier string
+	nonce    string
+	code     string
+}
+
+func authorize(t *testing.T, p *oidc.Provider, issuer *oidctest.Issuer, claims map[string]interface{}) *flow {
+	t.Helper()
+
+	f := &flow{
+		verifier: "verifier-" + t.Name() + "-0123456789abcdefghijklmnopqrstuvwxyz",
+		nonce:    "nonce-" + t.Name(),
+	}
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", f.nonce, oidc.CodeChallenge(f.verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	f.code, err = issuer.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatalf("authorizing: %v", err)
+	}
+	return f
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	issuer := newIssuer(t)
+	p := newProvider(issuer)
+
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", "nonce-1", oidc.CodeChallenge("verifier"))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := u.Scheme + "://" + u.Host + u.Path; got != issuer.URL+"/authorize" {
+		t.Errorf("endpoint = %q, want the discovered authorization endpoint", got)
+	}
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientId,
+		"redirect_uri":          testRedirectURL,
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        oidc.CodeChallenge("verifier"),
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if got := u.Query().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestExchange(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		want   oidc.Claims
+	}{
+		{
+			name: "profile claims",
+			claims: map[string]interface{}{
+				"email":              "user@example.com",
+				"email_verified":     true,
+				"preferred_username": "user",
+				"name":               "User One",
+			},
+			want: oidc.Claims{
+				Subject:           "subject-1",
+				Email:             "user@example.com",
+				EmailVerified:     true,
+				PreferredUsername: "user",
+				Name:              "User One",
+			},
+		},
+		{
+			name:   "email_verified as a string",
+			claims: map[string]interface{}{"email": "user@example.com", "email_verified": "true"},
+			want:   oidc.Claims{Subject: "subject-1", Email: "user@example.com", EmailVerified: true},
+		},
+		{
+			name:   "unverified email",
+			claims: map[string]interface{}{"email": "user@example.com", "email_verified": false},
+			want:   oidc.Claims{Subject: "subject-1", Email: "user@example.com"},
+		},
+		{
+			name:   "audience list with azp",
+			claims: map[string]interface{}{"aud": []string{testClientId, "api"}, "azp": testClientId},
+			want:   oidc.Claims{Subject: "subject-1"},
+		},
+		{
+			name:   "single audience with azp",
+			claims: map[string]interface{}{"azp": testClientId},
+			want:   oidc.Claims{Subject: "subject-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newIssuer(t)
+			p := newProvider(issuer)
+			f := authorize(t, p, issuer, tt.claims)
+
+			claims, err := p.Exchange(context.Background(), f.code, f.verifier, f.nonce)
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if *claims != tt.want {
+				t.Errorf("claims = %+v, want %+v", *claims, tt.want)
+			}
+		})
+	}
+}
+
+func TestExchangeRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		// change alters the values sent with the exchange
+		change func(f *flow)
+		// before runs against the issuer ahead of the exchange
+		before func(issuer *oidctest.Issuer)
+	}{
+		{
+			name:   "nonce mismatch",
+			change: func(f *flow) { f.nonce = "another nonce" },
+		},
+		{
+			name:   "no nonce in the token",
+			claims: map[string]interface{}{"nonce": nil},
+		},
+		{
+			name:   "empty nonce on both sides",
+			claims: map[string]interface{}{"nonce": ""},
+			change: func(f *flow) { f.nonce = "" },
+		},
+		{
+			name:   "wrong issuer",
+			claims: map[string]interface{}{"iss": "https://evil.example"},
+		},
+		{
+			name:   "no issuer",
+			claims: map[string]interface{}{"iss": nil},
+		},
+		{
+			name:   "wrong audience",
+			claims: map[string]interface{}{"aud": "client-2"},
+		},
+		{
+			name:   "audience list without the client",
+			claims: map[string]interface{}{"aud": []string{"client-2", "api"}, "azp": "client-2"},
+		},
+		{
+			name:   "audience list without azp",
+			claims: map[string]interface{}{"aud": []string{testClientId, "api"}},
+		},
+		{
+			name:   "audience list with a foreign azp",
+			claims: map[string]interface{}{"aud": []string{testClientId, "client-2"}, "azp": "client-2"},
+		},
+		{
+			name:   "single audience with a foreign azp",
+			claims: map[string]interface{}{"azp": "client-2"},
+		},
+		{
+			name:   "expired",
+			claims: map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()},
+		},
+		{
+			name:   "no expiry",
+			claims: map[string]interface{}{"exp": nil},
+		},
+		{
+			name:   "no subject",
+			claims: map[string]interface{}{"sub": nil},
+		},
+		{
+			name:   "wrong code verifier",
+			change: func(f *flow) { f.verifier = "verifier-of-another-login-0123456789abcdefghijklmn" },
+		},
+		{
+			name:   "no code verifier",
+			change: func(f *flow) { f.verifier = "" },
+		},
+		{
+			name:   "unknown code",
+			change: func(f *flow) { f.code = "forged" },
+		},
+		{
+			name:   "signed with an unpublished key",
+			before: func(issuer *oidctest.Issuer) { issuer.SignWithUnpublishedKey = true },
+		},
+		{
+			name:   "wrong client secret",
+			before: func(issuer *oidctest.Issuer) { issuer.ClientSecret = "rotated" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newIssuer(t)
+			p := newProvider(issuer)
+			f := authorize(t, p, issuer, tt.claims)
+			if tt.change != nil {
+				tt.change(f)
+			}
+			if tt.before != nil {
+				tt.before(issuer)
+			}
+
+			if claims, err := p.Exchange(context.Background(), f.code, f.verifier, f.nonce); err == nil {
+				t.Fatalf("Exchange = %+v, want an error", *claims)
+			}
+		})
+	}
+}
+
+func TestExchangeCodeReplayed(t *testing.T) {
+	issuer := newIssuer(t)
+	p := newProvider(issuer)
+	f := authorize(t, p, issuer, nil)
+
+	if _, err := p.Exchange(context.Background(), f.code, f.verifier, f.nonce); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := p.Exchange(context.Background(), f.code, f.verifier, f.nonce); err == nil {
+		t.Fatal("second Exchange of the same code succeeded")
+	}
+}
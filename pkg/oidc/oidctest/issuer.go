@@ -0,0 +1,256 @@
+// Package oidctest provides a local OpenID Connect provider serving discovery,
+// JWKS and the token endpoint of the authorization code flow with PKCE, for
+// tests.
+package oidctest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Issuer is the mock provider. It signs ID tokens with an ES256 key
+// published in its JWKS under KeyId.
+type Issuer struct {
+	URL          string
+	ClientId     string
+	ClientSecret string
+	KeyId        string
+	// SignWithUnpublishedKey signs the next ID tokens with a key that isn't in
+	// the JWKS, under the same kid
+	SignWithUnpublishedKey bool
+
+	server      *httptest.Server
+	key         *ecdsa.PrivateKey
+	unpublished *ecdsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+type authorization struct {
+	redirectURI   string
+	codeChallenge string
+	claims        jwt.MapClaims
+}
+
+// NewIssuer starts a provider for one client. An empty clientSecret makes it
+// a public client that authenticates with client_id only.
+func NewIssuer(clientId, clientSecret string) (*Issuer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	unpublished, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Issuer{
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		KeyId:        "test-key",
+		key:          key,
+		unpublished:  unpublished,
+		codes:        make(map[string]authorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", i.handleDiscovery)
+	mux.HandleFunc("/jwks", i.handleJWKS)
+	mux.HandleFunc("/token", i.handleToken)
+	i.server = httptest.NewServer(mux)
+	i.URL = i.server.URL
+
+	return i, nil
+}
+
+func (i *Issuer) Close() {
+	i.server.Close()
+}
+
+// Authorize stands in for the provider login page: it takes a URL built by
+// AuthCodeURL and returns the code the provider redirects back with. The ID
+// token of that code has the default claims with claims applied on top, a
+// nil value removes the claim.
+func (i *Issuer) Authorize(authURL string, claims map[string]interface{}) (string, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+
+	if query.Get("response_type") != "code" {
+		return "", errors.New("response_type must be code")
+	}
+	if query.Get("client_id") != i.ClientId {
+		return "", fmt.Errorf("unknown client %q", query.Get("client_id"))
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		return "", errors.New("an S256 code challenge is required")
+	}
+
+	now := time.Now()
+	idClaims := jwt.MapClaims{
+		"iss":   i.URL,
+		"sub":   "subject-1",
+		"aud":   i.ClientId,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(idClaims, name)
+			continue
+		}
+		idClaims[name] = value
+	}
+
+	code, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.codes[code] = authorization{
+		redirectURI:   query.Get("redirect_uri"),
+		codeChallenge: query.Get("code_challenge"),
+		claims:        idClaims,
+	}
+
+	return code, nil
+}
+
+func (i *Issuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 i.URL,
+		"authorization_endpoint": i.URL + "/authorize",
+		"token_endpoint":         i.URL + "/token",
+		"jwks_uri":               i.URL + "/jwks",
+	})
+}
+
+func (i *Issuer) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	point, err := i.key.PublicKey.ECDH()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// uncompressed point: 0x04 || x || y
+	raw := point.Bytes()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "EC",
+			"kid": i.KeyId,
+			"use": "sig",
+			"alg": "ES256",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(raw[1:33]),
+			"y":   base64.RawURLEncoding.EncodeToString(raw[33:]),
+		}},
+	})
+}
+
+// handleToken redeems a code once, checking the client, the redirect URI and
+// the PKCE verifier
+func (i *Issuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request", err.Error())
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type", "")
+		return
+	}
+
+	clientId := r.PostForm.Get("client_id")
+	if i.ClientSecret != "" {
+		user, password, ok := r.BasicAuth()
+		user, _ = url.QueryUnescape(user)
+		password, _ = url.QueryUnescape(password)
+		if !ok || user != i.ClientId || password != i.ClientSecret {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+			return
+		}
+		clientId = user
+	}
+	if clientId != i.ClientId {
+		tokenError(w, "invalid_client", "")
+		return
+	}
+
+	i.mu.Lock()
+	auth, ok := i.codes[r.PostForm.Get("code")]
+	delete(i.codes, r.PostForm.Get("code"))
+	i.mu.Unlock()
+
+	if !ok {
+		tokenError(w, "invalid_grant", "unknown or used code")
+		return
+	}
+	if r.PostForm.Get("redirect_uri") != auth.redirectURI {
+		tokenError(w, "invalid_grant", "redirect_uri doesn't match")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.codeChallenge {
+		tokenError(w, "invalid_grant", "PKCE verification failed")
+		return
+	}
+
+	key := i.key
+	if i.SignWithUnpublishedKey {
+		key = i.unpublished
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, auth.claims)
+	token.Header["kid"] = i.KeyId
+	idToken, err := token.SignedString(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
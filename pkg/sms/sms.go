@@ -0,0 +1,80 @@
+package sms
+
+import (
+	"auth/config"
+	"auth/pkg/logger"
+	"context"
+	"fmt"
+	"sync"
+)
+
+const (
+	// DriverLog writes every message to the log instead of sending it, for development.
+	DriverLog = "log"
+	// DriverMemory keeps messages in memory, for tests.
+	DriverMemory = "memory"
+)
+
+// Message ...
+type Message struct {
+	To   string
+	Text string
+}
+
+// SMSSender delivers text messages to phone numbers
+type SMSSender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender returns the sender selected by cfg.SMSDriver
+func NewSender(cfg config.Config, log logger.LoggerI) (SMSSender, error) {
+	switch cfg.SMSDriver {
+	case DriverLog:
+		return NewLogSender(log), nil
+	case DriverMemory:
+		return NewMemorySender(), nil
+	}
+	return nil, fmt.Errorf("unknown sms driver %q", cfg.SMSDriver)
+}
+
+// LogSender logs messages. Never use it in production, the log contains the codes.
+type LogSender struct {
+	log logger.LoggerI
+}
+
+// NewLogSender ...
+func NewLogSender(log logger.LoggerI) *LogSender {
+	return &LogSender{log: log}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	s.log.Info("sms", logger.String("to", msg.To), logger.String("text", msg.Text))
+	return nil
+}
+
+// MemorySender keeps sent messages so that tests can inspect them
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemorySender ...
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (s *MemorySender) Send(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of every message sent so far
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
@@ -0,0 +1,187 @@
+package validation
+
+import (
+	"auth/config"
+	"auth/pkg/helper"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// error codes returned to clients next to the field name
+const (
+	CodeRequired = "required"
+	CodeTooShort = "too_short"
+	CodeTooLong  = "too_long"
+	CodeFormat   = "invalid_format"
+	CodeReserved = "reserved"
+	CodeWeak     = "weak"
+	CodeBreached = "breached"
+	CodeTaken    = "taken"
+)
+
+// FieldError describes one failed check of one field
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors collects the failed checks of a request
+type Errors []FieldError
+
+func (e *Errors) Add(field, code, message string) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: message})
+}
+
+func (e Errors) Empty() bool {
+	return len(e) == 0
+}
+
+// Validator checks usernames, emails and passwords against the configured policies
+type Validator struct {
+	cfg      config.Config
+	reserved map[string]bool
+	breached map[string]bool
+}
+
+// New builds the validator and loads the breached password denylist, if configured
+func New(cfg config.Config) (*Validator, error) {
+	v := &Validator{
+		cfg:      cfg,
+		reserved: make(map[string]bool),
+		breached: make(map[string]bool),
+	}
+
+	for _, name := range cfg.ReservedUsernames {
+		v.reserved[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	if cfg.PasswordDenylistFile != "" {
+		file, err := os.Open(cfg.PasswordDenylistFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open password denylist: %w", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				v.breached[strings.ToLower(line)] = true
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read password denylist: %w", err)
+		}
+	}
+
+	return v, nil
+}
+
+// Username checks length, allowed characters and reserved names
+func (v *Validator) Username(errs *Errors, field, username string) {
+	if username == "" {
+		errs.Add(field, CodeRequired, "username is required")
+		return
+	}
+
+	if len(username) < v.cfg.UsernameMinLength {
+		errs.Add(field, CodeTooShort, fmt.Sprintf("username must be at least %d characters", v.cfg.UsernameMinLength))
+	}
+	if len(username) > v.cfg.UsernameMaxLength {
+		errs.Add(field, CodeTooLong, fmt.Sprintf("username must be at most %d characters", v.cfg.UsernameMaxLength))
+	}
+
+	first := rune(username[0])
+	if first > unicode.MaxASCII || !unicode.IsLetter(first) {
+		errs.Add(field, CodeFormat, "username must start with a latin letter")
+	}
+	for _, r := range username {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			errs.Add(field, CodeFormat, "username can contain only latin letters, digits and underscore")
+			break
+		}
+	}
+
+	if v.reserved[strings.ToLower(username)] {
+		errs.Add(field, CodeReserved, "username is reserved")
+	}
+}
+
+// Email checks the address format
+func (v *Validator) Email(errs *Errors, field, email string) {
+	if email == "" {
+		errs.Add(field, CodeRequired, "email is required")
+		return
+	}
+
+	if !helper.IsValidEmail(email) {
+		errs.Add(field, CodeFormat, "email is not valid")
+	}
+}
+
+// Phone checks that the number is an Uzbek +998 number
+func (v *Validator) Phone(errs *Errors, field, phone string) {
+	if phone == "" {
+		errs.Add(field, CodeRequired, "phone is required")
+		return
+	}
+
+	if !helper.IsValidPhone(phone) {
+		errs.Add(field, CodeFormat, "phone must look like +998XXXXXXXXX")
+	}
+}
+
+// Password checks length, complexity and the breached password denylist.
+// The username is passed so that the password can't simply repeat it.
+func (v *Validator) Password(errs *Errors, field, password, username string) {
+	if password == "" {
+		errs.Add(field, CodeRequired, "password is required")
+		return
+	}
+
+	if len(password) < v.cfg.PasswordMinLength {
+		errs.Add(field, CodeTooShort, fmt.Sprintf("password must be at least %d characters", v.cfg.PasswordMinLength))
+	}
+	// bcrypt ignores everything after 72 bytes
+	if len(password) > 72 {
+		errs.Add(field, CodeTooLong, "password must be at most 72 bytes")
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if v.cfg.PasswordRequireUpper && !hasUpper {
+		errs.Add(field, CodeWeak, "password must contain an uppercase letter")
+	}
+	if v.cfg.PasswordRequireLower && !hasLower {
+		errs.Add(field, CodeWeak, "password must contain a lowercase letter")
+	}
+	if v.cfg.PasswordRequireDigit && !hasDigit {
+		errs.Add(field, CodeWeak, "password must contain a digit")
+	}
+	if v.cfg.PasswordRequireSymbol && !hasSymbol {
+		errs.Add(field, CodeWeak, "password must contain a symbol")
+	}
+
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		errs.Add(field, CodeWeak, "password must not contain the username")
+	}
+
+	if v.breached[strings.ToLower(password)] {
+		errs.Add(field, CodeBreached, "password is known from data breaches, choose another one")
+	}
+}
@@ -0,0 +1,442 @@
+// Package webauthn implements the relying party side of the WebAuthn
+// registration and authentication ceremonies for passkeys.
+//
+// Attestation is not verified: registration asks for "none" conveyance and
+// any authenticator model is accepted.
+package webauthn
+
+import (
+	"auth/config"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// authenticator data flags
+const (
+	flagUserPresent        = 0x01
+	flagUserVerified       = 0x04
+	flagAttestedCredential = 0x40
+	flagExtensions         = 0x80
+)
+
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+)
+
+var (
+	ErrInvalidCredential  = errors.New("invalid webauthn credential")
+	ErrSignCountRegressed = errors.New("webauthn signature counter went back")
+)
+
+// RelyingParty is this service as WebAuthn sees it. ID is the domain passkeys
+// are bound to and Origin the web origin the ceremonies run on.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origin  string
+	Timeout time.Duration
+}
+
+func NewRelyingParty(cfg config.Config) *RelyingParty {
+	return &RelyingParty{
+		ID:      cfg.WebAuthnRPID,
+		Name:    cfg.WebAuthnRPName,
+		Origin:  cfg.WebAuthnOrigin,
+		Timeout: config.WebAuthnChallengeExpireTime,
+	}
+}
+
+// NewChallenge returns a random base64url encoded challenge
+func NewChallenge() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// EncodeID encodes credential ids and user handles the way browsers send them
+func EncodeID(id []byte) string {
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+// DecodeID accepts base64url with or without padding
+func DecodeID(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+type User struct {
+	ID          string
+	Name        string
+	DisplayName string
+}
+
+type rpEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type userEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type credentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	RequireResident  bool   `json:"requireResidentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions is passed to navigator.credentials.create() as publicKey.
+// Binary fields are base64url encoded.
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     rpEntity               `json:"rp"`
+	User                   userEntity             `json:"user"`
+	PubKeyCredParams       []credentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	Attestation            string                 `json:"attestation"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+}
+
+// RequestOptions is passed to navigator.credentials.get() as publicKey. No
+// credentials are listed, the authenticator offers the passkeys it has for us.
+type RequestOptions struct {
+	Challenge        string `json:"challenge"`
+	RPID             string `json:"rpId"`
+	Timeout          int64  `json:"timeout"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions asks for a discoverable credential so it can be used
+// without typing a username. exclude lists credentials the user already has.
+func (rp *RelyingParty) CreationOptions(challenge string, user User, exclude [][]byte) *CreationOptions {
+	options := &CreationOptions{
+		Challenge: challenge,
+		RP:        rpEntity{ID: rp.ID, Name: rp.Name},
+		User: userEntity{
+			ID:          EncodeID([]byte(user.ID)),
+			Name:        user.Name,
+			DisplayName: user.DisplayName,
+		},
+		PubKeyCredParams: []credentialParameter{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgEdDSA},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:            rp.Timeout.Milliseconds(),
+		Attestation:        "none",
+		ExcludeCredentials: make([]CredentialDescriptor, 0, len(exclude)),
+		AuthenticatorSelection: authenticatorSelection{
+			ResidentKey:      "required",
+			RequireResident:  true,
+			UserVerification: "preferred",
+		},
+	}
+	for _, id := range exclude {
+		options.ExcludeCredentials = append(options.ExcludeCredentials, CredentialDescriptor{Type: "public-key", ID: EncodeID(id)})
+	}
+
+	return options
+}
+
+func (rp *RelyingParty) RequestOptions(challenge string) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		RPID:             rp.ID,
+		Timeout:          rp.Timeout.Milliseconds(),
+		UserVerification: "preferred",
+	}
+}
+
+// AttestationResponse is the PublicKeyCredential returned by navigator.credentials.create()
+type AttestationResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the PublicKeyCredential returned by navigator.credentials.get()
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Credential is a verified new credential. Challenge has to be checked against
+// the one handed out before the credential is stored.
+type Credential struct {
+	ID           []byte
+	PublicKey    []byte
+	SignCount    uint32
+	UserVerified bool
+	Challenge    string
+}
+
+// Assertion is a verified login. Challenge has to be checked against the one
+// handed out and SignCount against the stored counter.
+type Assertion struct {
+	CredentialID []byte
+	UserHandle   string
+	SignCount    uint32
+	UserVerified bool
+	Challenge    string
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type authenticatorData struct {
+	rpIdHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// VerifyRegistration checks a new credential and returns what has to be stored
+func (rp *RelyingParty) VerifyRegistration(resp *AttestationResponse) (*Credential, error) {
+	if resp.Type != "public-key" {
+		return nil, fmt.Errorf("%w: unexpected type %q", ErrInvalidCredential, resp.Type)
+	}
+
+	client, err := rp.verifyClientData(resp.Response.ClientDataJSON, ceremonyCreate)
+	if err != nil {
+		return nil, err
+	}
+
+	rawAttestation, err := DecodeID(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: attestation object is not base64url", ErrInvalidCredential)
+	}
+	value, _, err := decodeCBOR(rawAttestation)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+	attestation, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: attestation object is not a map", ErrInvalidCredential)
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: attestation object has no authenticator data", ErrInvalidCredential)
+	}
+
+	authData, err := rp.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.flags&flagAttestedCredential == 0 {
+		return nil, fmt.Errorf("%w: no attested credential data", ErrInvalidCredential)
+	}
+	if _, _, err = parsePublicKey(authData.publicKey); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+
+	rawID, err := DecodeID(resp.RawID)
+	if err != nil || !bytes.Equal(rawID, authData.credentialID) {
+		return nil, fmt.Errorf("%w: credential id doesn't match the authenticator data", ErrInvalidCredential)
+	}
+
+	return &Credential{
+		ID:           authData.credentialID,
+		PublicKey:    authData.publicKey,
+		SignCount:    authData.signCount,
+		UserVerified: authData.flags&flagUserVerified != 0,
+		Challenge:    client.Challenge,
+	}, nil
+}
+
+// VerifyAssertion checks a login signature with the stored COSE public key of the credential
+func (rp *RelyingParty) VerifyAssertion(resp *AssertionResponse, publicKey []byte) (*Assertion, error) {
+	if resp.Type != "public-key" {
+		return nil, fmt.Errorf("%w: unexpected type %q", ErrInvalidCredential, resp.Type)
+	}
+
+	client, err := rp.verifyClientData(resp.Response.ClientDataJSON, ceremonyGet)
+	if err != nil {
+		return nil, err
+	}
+
+	rawAuthData, err := DecodeID(resp.Response.AuthenticatorData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: authenticator data is not base64url", ErrInvalidCredential)
+	}
+	authData, err := rp.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	rawClientData, _ := DecodeID(resp.Response.ClientDataJSON)
+	signature, err := DecodeID(resp.Response.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature is not base64url", ErrInvalidCredential)
+	}
+	pub, _, err := parsePublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+
+	clientDataHash := sha256.Sum256(rawClientData)
+	signed := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+	if err = verifySignature(pub, signed, signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+
+	credentialID, err := DecodeID(resp.RawID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: credential id is not base64url", ErrInvalidCredential)
+	}
+	userHandle, err := DecodeID(resp.Response.UserHandle)
+	if err != nil {
+		return nil, fmt.Errorf("%w: user handle is not base64url", ErrInvalidCredential)
+	}
+
+	return &Assertion{
+		CredentialID: credentialID,
+		UserHandle:   string(userHandle),
+		SignCount:    authData.signCount,
+		UserVerified: authData.flags&flagUserVerified != 0,
+		Challenge:    client.Challenge,
+	}, nil
+}
+
+// verifyClientData checks the ceremony type and origin the browser signed
+func (rp *RelyingParty) verifyClientData(encoded, ceremony string) (*clientData, error) {
+	raw, err := DecodeID(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: client data is not base64url", ErrInvalidCredential)
+	}
+
+	var client clientData
+	if err = json.Unmarshal(raw, &client); err != nil {
+		return nil, fmt.Errorf("%w: client data is not json", ErrInvalidCredential)
+	}
+
+	if client.Type != ceremony {
+		return nil, fmt.Errorf("%w: unexpected ceremony %q", ErrInvalidCredential, client.Type)
+	}
+	if client.Challenge == "" {
+		return nil, fmt.Errorf("%w: missing challenge", ErrInvalidCredential)
+	}
+	if client.CrossOrigin || !sameOrigin(client.Origin, rp.Origin) {
+		return nil, fmt.Errorf("%w: unexpected origin %q", ErrInvalidCredential, client.Origin)
+	}
+
+	return &client, nil
+}
+
+// verifyAuthenticatorData parses the authenticator data and checks that it was
+// made for our relying party id with the user present
+func (rp *RelyingParty) verifyAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, fmt.Errorf("%w: authenticator data is too short", ErrInvalidCredential)
+	}
+
+	authData := &authenticatorData{
+		rpIdHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	rpIdHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(authData.rpIdHash, rpIdHash[:]) != 1 {
+		return nil, fmt.Errorf("%w: credential is for another relying party", ErrInvalidCredential)
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return nil, fmt.Errorf("%w: user was not present", ErrInvalidCredential)
+	}
+
+	rest := data[37:]
+	if authData.flags&flagAttestedCredential != 0 {
+		// aaguid, credential id length and id, then the COSE public key
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: attested credential data is too short", ErrInvalidCredential)
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength == 0 || idLength > 1023 || len(rest) < idLength {
+			return nil, fmt.Errorf("%w: invalid credential id length", ErrInvalidCredential)
+		}
+		authData.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: public key: %v", ErrInvalidCredential, err)
+		}
+		authData.publicKey = rest[:n]
+		rest = rest[n:]
+	}
+	if authData.flags&flagExtensions != 0 {
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: extensions: %v", ErrInvalidCredential, err)
+		}
+		rest = rest[n:]
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing authenticator data", ErrInvalidCredential)
+	}
+
+	return authData, nil
+}
+
+// CheckSignCount compares the signature counter of an assertion with the one
+// stored for the credential. A counter that doesn't move forward means the
+// authenticator was cloned; authenticators that don't count always send zero.
+func CheckSignCount(stored, received uint32) error {
+	if (received != 0 || stored != 0) && received <= stored {
+		return ErrSignCountRegressed
+	}
+	return nil
+}
+
+func sameOrigin(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Scheme == ub.Scheme && strings.EqualFold(ua.Host, ub.Host)
+}
@@ -0,0 +1,365 @@
+package webauthn_test
+
+import (
+	"auth/pkg/webauthn"
+	"auth/pkg/webauthn/webauthntest"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+var algorithms = map[string]int{
+	"ES256": webauthn.AlgES256,
+	"EdDSA": webauthn.AlgEdDSA,
+}
+
+func newRelyingParty() *webauthn.RelyingParty {
+	return &webauthn.RelyingParty{
+		ID:      testRPID,
+		Name:    "Example",
+		Origin:  testOrigin,
+		Timeout: time.Minute,
+	}
+}
+
+func newAuthenticator(t *testing.T, alg int) *webauthntest.Authenticator {
+	t.Helper()
+
+	a, err := webauthntest.New(testRPID, testOrigin, alg)
+	if err != nil {
+		t.Fatalf("creating authenticator: %v", err)
+	}
+	return a
+}
+
+func newChallenge(t *testing.T) string {
+	t.Helper()
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatalf("creating challenge: %v", err)
+	}
+	return challenge
+}
+
+// register runs a registration and returns the verified credential
+func register(t *testing.T, rp *webauthn.RelyingParty, a *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+
+	resp, err := a.Register(newChallenge(t), []byte("user-1"))
+	if err != nil {
+		t.Fatalf("registering: %v", err)
+	}
+	credential, err := rp.VerifyRegistration(resp)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	return credential
+}
+
+func TestRegistration(t *testing.T) {
+	for name, alg := range algorithms {
+		t.Run(name, func(t *testing.T) {
+			rp := newRelyingParty()
+			a := newAuthenticator(t, alg)
+			challenge := newChallenge(t)
+
+			resp, err := a.Register(challenge, []byte("user-1"))
+			if err != nil {
+				t.Fatalf("registering: %v", err)
+			}
+			credential, err := rp.VerifyRegistration(resp)
+			if err != nil {
+				t.Fatalf("VerifyRegistration: %v", err)
+			}
+
+			if credential.Challenge != challenge {
+				t.Errorf("challenge = %q, want %q", credential.Challenge, challenge)
+			}
+			if !bytes.Equal(credential.ID, a.CredentialID) {
+				t.Errorf("credential id = %x, want %x", credential.ID, a.CredentialID)
+			}
+			if !credential.UserVerified {
+				t.Error("user verification flag was lost")
+			}
+			if credential.SignCount != 0 {
+				t.Errorf("sign count = %d, want 0", credential.SignCount)
+			}
+		})
+	}
+}
+
+func TestRegistrationRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		before func(a *webauthntest.Authenticator)
+		after  func(resp *webauthn.AttestationResponse)
+	}{
+		{
+			name:   "wrong origin",
+			before: func(a *webauthntest.Authenticator) { a.Origin = "https://evil.example" },
+		},
+		{
+			name:   "subdomain origin",
+			before: func(a *webauthntest.Authenticator) { a.Origin = "https://login.example.com" },
+		},
+		{
+			name:   "insecure origin",
+			before: func(a *webauthntest.Authenticator) { a.Origin = "http://example.com" },
+		},
+		{
+			name:   "wrong relying party id",
+			before: func(a *webauthntest.Authenticator) { a.RPID = "evil.example" },
+		},
+		{
+			name:   "assertion ceremony",
+			before: func(a *webauthntest.Authenticator) { a.Ceremony = "webauthn.get" },
+		},
+		{
+			name: "empty challenge",
+			after: func(resp *webauthn.AttestationResponse) {
+				resp.Response.ClientDataJSON = webauthn.EncodeID([]byte(`{"type":"webauthn.create","challenge":"","origin":"https://example.com"}`))
+			},
+		},
+		{
+			name:  "raw id of another credential",
+			after: func(resp *webauthn.AttestationResponse) { resp.RawID = webauthn.EncodeID([]byte("another credential")) },
+		},
+		{
+			name: "truncated attestation object",
+			after: func(resp *webauthn.AttestationResponse) {
+				resp.Response.AttestationObject = resp.Response.AttestationObject[:len(resp.Response.AttestationObject)/2]
+			},
+		},
+		{
+			name:  "not a public key credential",
+			after: func(resp *webauthn.AttestationResponse) { resp.Type = "password" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAuthenticator(t, webauthn.AlgES256)
+			if tt.before != nil {
+				tt.before(a)
+			}
+			resp, err := a.Register(newChallenge(t), []byte("user-1"))
+			if err != nil {
+				t.Fatalf("registering: %v", err)
+			}
+			if tt.after != nil {
+				tt.after(resp)
+			}
+
+			_, err = newRelyingParty().VerifyRegistration(resp)
+			if !errors.Is(err, webauthn.ErrInvalidCredential) {
+				t.Fatalf("VerifyRegistration error = %v, want ErrInvalidCredential", err)
+			}
+		})
+	}
+}
+
+func TestAssertion(t *testing.T) {
+	for name, alg := range algorithms {
+		t.Run(name, func(t *testing.T) {
+			rp := newRelyingParty()
+			a := newAuthenticator(t, alg)
+			credential := register(t, rp, a)
+
+			stored := credential.SignCount
+			for i := 0; i < 3; i++ {
+				challenge := newChallenge(t)
+				resp, err := a.Assert(challenge)
+				if err != nil {
+					t.Fatalf("asserting: %v", err)
+				}
+				assertion, err := rp.VerifyAssertion(resp, credential.PublicKey)
+				if err != nil {
+					t.Fatalf("VerifyAssertion: %v", err)
+				}
+
+				if assertion.Challenge != challenge {
+					t.Errorf("challenge = %q, want %q", assertion.Challenge, challenge)
+				}
+				if !bytes.Equal(assertion.CredentialID, a.CredentialID) {
+					t.Errorf("credential id = %x, want %x", assertion.CredentialID, a.CredentialID)
+				}
+				if assertion.UserHandle != "user-1" {
+					t.Errorf("user handle = %q, want %q", assertion.UserHandle, "user-1")
+				}
+				if err = webauthn.CheckSignCount(stored, assertion.SignCount); err != nil {
+					t.Fatalf("CheckSignCount(%d, %d): %v", stored, assertion.SignCount, err)
+				}
+				stored = assertion.SignCount
+			}
+		})
+	}
+}
+
+func TestAssertionRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		before func(a *webauthntest.Authenticator)
+		after  func(t *testing.T, resp *webauthn.AssertionResponse)
+	}{
+		{
+			name:   "wrong origin",
+			before: func(a *webauthntest.Authenticator) { a.Origin = "https://evil.example" },
+		},
+		{
+			name:   "wrong relying party id",
+			before: func(a *webauthntest.Authenticator) { a.RPID = "evil.example" },
+		},
+		{
+			name:   "registration ceremony",
+			before: func(a *webauthntest.Authenticator) { a.Ceremony = "webauthn.create" },
+		},
+		{
+			name: "bad signature",
+			after: func(t *testing.T, resp *webauthn.AssertionResponse) {
+				resp.Response.Signature = flipLastByte(t, resp.Response.Signature)
+			},
+		},
+		{
+			name: "authenticator data changed after signing",
+			after: func(t *testing.T, resp *webauthn.AssertionResponse) {
+				resp.Response.AuthenticatorData = flipLastByte(t, resp.Response.AuthenticatorData)
+			},
+		},
+		{
+			name: "challenge swapped after signing",
+			after: func(t *testing.T, resp *webauthn.AssertionResponse) {
+				raw, err := webauthn.DecodeID(resp.Response.ClientDataJSON)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var clientData map[string]interface{}
+				if err = json.Unmarshal(raw, &clientData); err != nil {
+					t.Fatal(err)
+				}
+				clientData["challenge"] = newChallenge(t)
+				swapped, err := json.Marshal(clientData)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Response.ClientDataJSON = webauthn.EncodeID(swapped)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := newRelyingParty()
+			a := newAuthenticator(t, webauthn.AlgES256)
+			credential := register(t, rp, a)
+
+			if tt.before != nil {
+				tt.before(a)
+			}
+			resp, err := a.Assert(newChallenge(t))
+			if err != nil {
+				t.Fatalf("asserting: %v", err)
+			}
+			if tt.after != nil {
+				tt.after(t, resp)
+			}
+
+			_, err = rp.VerifyAssertion(resp, credential.PublicKey)
+			if !errors.Is(err, webauthn.ErrInvalidCredential) {
+				t.Fatalf("VerifyAssertion error = %v, want ErrInvalidCredential", err)
+			}
+		})
+	}
+}
+
+func TestAssertionSignedByAnotherKey(t *testing.T) {
+	rp := newRelyingParty()
+	credential := register(t, rp, newAuthenticator(t, webauthn.AlgES256))
+
+	// same credential id, different private key
+	other := newAuthenticator(t, webauthn.AlgES256)
+	resp, err := other.Assert(newChallenge(t))
+	if err != nil {
+		t.Fatalf("asserting: %v", err)
+	}
+
+	_, err = rp.VerifyAssertion(resp, credential.PublicKey)
+	if !errors.Is(err, webauthn.ErrInvalidCredential) {
+		t.Fatalf("VerifyAssertion error = %v, want ErrInvalidCredential", err)
+	}
+}
+
+func TestCheckSignCount(t *testing.T) {
+	tests := []struct {
+		stored, received uint32
+		regressed        bool
+	}{
+		{stored: 0, received: 0},
+		{stored: 0, received: 1},
+		{stored: 5, received: 6},
+		{stored: 5, received: 100},
+		{stored: 5, received: 5, regressed: true},
+		{stored: 5, received: 4, regressed: true},
+		{stored: 5, received: 0, regressed: true},
+	}
+
+	for _, tt := range tests {
+		err := webauthn.CheckSignCount(tt.stored, tt.received)
+		if regressed := errors.Is(err, webauthn.ErrSignCountRegressed); regressed != tt.regressed {
+			t.Errorf("CheckSignCount(%d, %d) = %v, want regressed %v", tt.stored, tt.received, err, tt.regressed)
+		}
+	}
+}
+
+// TestSignCountRegression replays an older assertion of a cloned authenticator
+func TestSignCountRegression(t *testing.T) {
+	rp := newRelyingParty()
+	a := newAuthenticator(t, webauthn.AlgES256)
+	credential := register(t, rp, a)
+	a.SignCount = 10
+
+	first, err := a.Assert(newChallenge(t))
+	if err != nil {
+		t.Fatalf("asserting: %v", err)
+	}
+	assertion, err := rp.VerifyAssertion(first, credential.PublicKey)
+	if err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+	if err = webauthn.CheckSignCount(credential.SignCount, assertion.SignCount); err != nil {
+		t.Fatalf("CheckSignCount: %v", err)
+	}
+	stored := assertion.SignCount
+
+	// the clone still has the old counter
+	a.SignCount = 3
+	cloned, err := a.Assert(newChallenge(t))
+	if err != nil {
+		t.Fatalf("asserting: %v", err)
+	}
+	assertion, err = rp.VerifyAssertion(cloned, credential.PublicKey)
+	if err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+	if err = webauthn.CheckSignCount(stored, assertion.SignCount); !errors.Is(err, webauthn.ErrSignCountRegressed) {
+		t.Fatalf("CheckSignCount(%d, %d) = %v, want ErrSignCountRegressed", stored, assertion.SignCount, err)
+	}
+}
+
+func flipLastByte(t *testing.T, encoded string) string {
+	t.Helper()
+
+	raw, err := webauthn.DecodeID(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[len(raw)-1] ^= 0xff
+	return webauthn.EncodeID(raw)
+}
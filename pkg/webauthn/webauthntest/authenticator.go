@@ -0,0 +1,235 @@
+// Package webauthntest provides a software authenticator that runs the
+// authenticator side of the WebAuthn ceremonies, for tests.
+package webauthntest
+
+import (
+	"auth/pkg/webauthn"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// authenticator data flags
+const (
+	flagUserPresent        = 0x01
+	flagUserVerified       = 0x04
+	flagAttestedCredential = 0x40
+)
+
+// Authenticator holds one discoverable credential. The fields can be changed
+// between ceremonies to make it misbehave, e.g. answer for another origin.
+type Authenticator struct {
+	RPID   string
+	Origin string
+	// Ceremony overrides the client data type when it isn't empty
+	Ceremony     string
+	UserVerified bool
+	// SignCount is sent with the next assertion, which then moves it forward
+	SignCount    uint32
+	CredentialID []byte
+	UserHandle   []byte
+
+	alg        int
+	ecdsaKey   *ecdsa.PrivateKey
+	ed25519Key ed25519.PrivateKey
+}
+
+// New creates an authenticator with a fresh key pair for the COSE algorithm,
+// webauthn.AlgES256 or webauthn.AlgEdDSA
+func New(rpID, origin string, alg int) (*Authenticator, error) {
+	a := &Authenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		UserVerified: true,
+		CredentialID: make([]byte, 16),
+		alg:          alg,
+	}
+	if _, err := rand.Read(a.CredentialID); err != nil {
+		return nil, err
+	}
+
+	var err error
+	switch alg {
+	case webauthn.AlgES256:
+		a.ecdsaKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case webauthn.AlgEdDSA:
+		_, a.ed25519Key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %d", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Register answers navigator.credentials.create() for the challenge with a
+// "none" attestation
+func (a *Authenticator) Register(challenge string, userHandle []byte) (*webauthn.AttestationResponse, error) {
+	a.UserHandle = userHandle
+
+	clientData, err := a.clientData("webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := a.publicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	authData := a.authenticatorData(flagAttestedCredential)
+	authData = append(authData, make([]byte, 16)...) // aaguid
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.CredentialID)))
+	authData = append(authData, a.CredentialID...)
+	authData = append(authData, publicKey...)
+
+	attestation := cborMap(
+		cborText("fmt"), cborText("none"),
+		cborText("attStmt"), cborMap(),
+		cborText("authData"), cborBytes(authData),
+	)
+
+	resp := &webauthn.AttestationResponse{
+		ID:    webauthn.EncodeID(a.CredentialID),
+		RawID: webauthn.EncodeID(a.CredentialID),
+		Type:  "public-key",
+	}
+	resp.Response.ClientDataJSON = webauthn.EncodeID(clientData)
+	resp.Response.AttestationObject = webauthn.EncodeID(attestation)
+
+	return resp, nil
+}
+
+// Assert answers navigator.credentials.get() for the challenge, signing with
+// the current SignCount and then moving it forward
+func (a *Authenticator) Assert(challenge string) (*webauthn.AssertionResponse, error) {
+	clientData, err := a.clientData("webauthn.get", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	authData := a.authenticatorData(0)
+	clientDataHash := sha256.Sum256(clientData)
+	signature, err := a.sign(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	if err != nil {
+		return nil, err
+	}
+	a.SignCount++
+
+	resp := &webauthn.AssertionResponse{
+		ID:    webauthn.EncodeID(a.CredentialID),
+		RawID: webauthn.EncodeID(a.CredentialID),
+		Type:  "public-key",
+	}
+	resp.Response.ClientDataJSON = webauthn.EncodeID(clientData)
+	resp.Response.AuthenticatorData = webauthn.EncodeID(authData)
+	resp.Response.Signature = webauthn.EncodeID(signature)
+	resp.Response.UserHandle = webauthn.EncodeID(a.UserHandle)
+
+	return resp, nil
+}
+
+func (a *Authenticator) clientData(ceremony, challenge string) ([]byte, error) {
+	if a.Ceremony != "" {
+		ceremony = a.Ceremony
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    a.Origin,
+	})
+}
+
+// authenticatorData returns the rp id hash, flags and counter every
+// authenticator data starts with
+func (a *Authenticator) authenticatorData(flags byte) []byte {
+	flags |= flagUserPresent
+	if a.UserVerified {
+		flags |= flagUserVerified
+	}
+
+	rpIdHash := sha256.Sum256([]byte(a.RPID))
+	data := append(rpIdHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.SignCount)
+}
+
+// publicKey encodes the credential public key as a COSE key
+func (a *Authenticator) publicKey() ([]byte, error) {
+	if a.ed25519Key != nil {
+		return cborMap(
+			cborInt(1), cborInt(1), // kty: OKP
+			cborInt(3), cborInt(webauthn.AlgEdDSA),
+			cborInt(-1), cborInt(6), // crv: Ed25519
+			cborInt(-2), cborBytes(a.ed25519Key.Public().(ed25519.PublicKey)),
+		), nil
+	}
+
+	key, err := a.ecdsaKey.PublicKey.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	// uncompressed point: 0x04 || x || y
+	point := key.Bytes()
+	return cborMap(
+		cborInt(1), cborInt(2), // kty: EC2
+		cborInt(3), cborInt(webauthn.AlgES256),
+		cborInt(-1), cborInt(1), // crv: P-256
+		cborInt(-2), cborBytes(point[1:33]),
+		cborInt(-3), cborBytes(point[33:]),
+	), nil
+}
+
+func (a *Authenticator) sign(data []byte) ([]byte, error) {
+	if a.ed25519Key != nil {
+		return ed25519.Sign(a.ed25519Key, data), nil
+	}
+
+	digest := sha256.Sum256(data)
+	return ecdsa.SignASN1(rand.Reader, a.ecdsaKey, digest[:])
+}
+
+// cborHead encodes the initial byte and argument of a CBOR item
+func cborHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, arg)
+}
+
+func cborInt(n int64) []byte {
+	if n < 0 {
+		return cborHead(1, uint64(-1-n))
+	}
+	return cborHead(0, uint64(n))
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, uint64(len(b))), b...)
+}
+
+func cborText(s string) []byte {
+	return append(cborHead(3, uint64(len(s))), s...)
+}
+
+// cborMap encodes a map from its keys and values, in order
+func cborMap(items ...[]byte) []byte {
+	out := cborHead(5, uint64(len(items)/2))
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"auth/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type authEventRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAuthEventRepo(db *pgxpool.Pool) *authEventRepo {
+	return &authEventRepo{
+		db: db,
+	}
+}
+
+func (b *authEventRepo) CreateAuthEvent(c context.Context, req *models.CreateAuthEvent) error {
+	query := `
+		INSERT INTO "auth_events"(
+			"id",
+			"user_id",
+			"actor_id",
+			"event_type",
+			"result",
+			"detail",
+			"ip",
+			"user_agent",
+			"created_at")
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, NULLIF($6, ''), $7, LEFT($8, 512), NOW())
+	`
+	_, err := b.db.Exec(c, query,
+		uuid.NewString(),
+		req.UserId,
+		req.ActorId,
+		req.EventType,
+		req.Result,
+		req.Detail,
+		req.IP,
+		req.UserAgent,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create auth event: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuthEvents returns the newest events first
+func (b *authEventRepo) GetAuthEvents(c context.Context, req *models.GetAuthEventsRequest) (*models.GetAllAuthEvents, error) {
+	var (
+		filters []string
+		args    []interface{}
+	)
+	addFilter := func(filter string, arg interface{}) {
+		args = append(args, arg)
+		filters = append(filters, fmt.Sprintf(filter, "$"+strconv.Itoa(len(args))))
+	}
+
+	if req.UserId != "" {
+		addFilter(`"user_id" = %s`, req.UserId)
+	}
+	if req.EventType != "" {
+		addFilter(`"event_type" = %s`, req.EventType)
+	}
+	if !req.From.IsZero() {
+		addFilter(`"created_at" >= %s`, req.From)
+	}
+	if !req.To.IsZero() {
+		addFilter(`"created_at" < %s`, req.To)
+	}
+
+	where := ""
+	if len(filters) > 0 {
+		where = "WHERE " + strings.Join(filters, " AND ")
+	}
+
+	args = append(args, (req.Page-1)*req.Limit, req.Limit)
+	query := fmt.Sprintf(`
+		SELECT
+			COUNT(*) OVER(),
+			"id",
+			"user_id",
+			"actor_id",
+			"event_type",
+			"result",
+			"detail",
+			"ip",
+			"user_agent",
+			"created_at"
+		FROM "auth_events"
+		%s
+		ORDER BY "created_at" DESC
+		OFFSET $%d LIMIT $%d
+	`, where, len(args)-1, len(args))
+
+	rows, err := b.db.Query(c, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	resp := &models.GetAllAuthEvents{Events: make([]models.AuthEvent, 0)}
+	for rows.Next() {
+		var (
+			user_id, actor_id, detail, ip, user_agent sql.NullString
+			created_at                                time.Time
+		)
+
+		event := models.AuthEvent{}
+		err := rows.Scan(
+			&resp.Count,
+			&event.ID,
+			&user_id,
+			&actor_id,
+			&event.EventType,
+			&event.Result,
+			&detail,
+			&ip,
+			&user_agent,
+			&created_at,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		event.UserId = user_id.String
+		event.ActorId = actor_id.String
+		event.Detail = detail.String
+		event.IP = ip.String
+		event.UserAgent = user_agent.String
+		event.CreatedAt = created_at.Format(time.RFC3339)
+
+		resp.Events = append(resp.Events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"auth/models"
+	"auth/storage"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type blockRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewBlockRepo(db *pgxpool.Pool) *blockRepo {
+	return &blockRepo{
+		db: db,
+	}
+}
+
+// Block blocks a user and ends the follows and follow requests between the
+// two users in both directions. Blocking the same user twice does nothing.
+func (b *blockRepo) Block(c context.Context, req *models.Block) error {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	query := `
+		INSERT INTO "user_blocks" ("id", "blocker_id", "blocked_id", "created_at")
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT ("blocker_id", "blocked_id") DO NOTHING
+	`
+	if _, err = tx.Exec(c, query, uuid.NewString(), req.BlockerId, req.BlockedId); err != nil {
+		return fmt.Errorf("failed to block user: %w", err)
+	}
+
+	query = `
+		UPDATE "follows"
+		SET
+			"deleted_at" = NOW()
+		WHERE
+			"deleted_at" IS NULL AND (
+				("follower_id" = $1 AND "following_id" = $2) OR
+				("follower_id" = $2 AND "following_id" = $1)
+			)
+	`
+	if _, err = tx.Exec(c, query, req.BlockerId, req.BlockedId); err != nil {
+		return fmt.Errorf("failed to remove follows: %w", err)
+	}
+
+	query = `
+		DELETE FROM "follow_requests"
+		WHERE
+			("requester_id" = $1 AND "target_id" = $2) OR
+			("requester_id" = $2 AND "target_id" = $1)
+	`
+	if _, err = tx.Exec(c, query, req.BlockerId, req.BlockedId); err != nil {
+		return fmt.Errorf("failed to remove follow requests: %w", err)
+	}
+
+	return tx.Commit(c)
+}
+
+func (b *blockRepo) Unblock(c context.Context, req *models.Block) error {
+	query := `DELETE FROM "user_blocks" WHERE "blocker_id" = $1 AND "blocked_id" = $2`
+
+	result, err := b.db.Exec(c, query, req.BlockerId, req.BlockedId)
+	if err != nil {
+		return fmt.Errorf("failed to unblock user: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return storage.ErrNotBlocked
+	}
+
+	return nil
+}
+
+// IsBlocked reports whether either of the two users has blocked the other
+func (b *blockRepo) IsBlocked(c context.Context, userId, otherId string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM "user_blocks"
+			WHERE
+				("blocker_id" = $1 AND "blocked_id" = $2) OR
+				("blocker_id" = $2 AND "blocked_id" = $1)
+		)
+	`
+
+	blocked := false
+	err := b.db.QueryRow(c, query, userId, otherId).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check block: %w", err)
+	}
+
+	return blocked, nil
+}
+
+// GetBlocks lists the users req.UserId has blocked, newest first
+func (b *blockRepo) GetBlocks(c context.Context, req *models.GetBlocksRequest) (*models.GetAllBlocks, error) {
+	return b.listUsers(c, req, "user_blocks", "blocker_id", "blocked_id")
+}
+
+// Mute hides the content of a user for the muter only. Muting the same user
+// twice does nothing.
+func (b *blockRepo) Mute(c context.Context, req *models.Mute) error {
+	query := `
+		INSERT INTO "user_mutes" ("id", "muter_id", "muted_id", "created_at")
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT ("muter_id", "muted_id") DO NOTHING
+	`
+
+	_, err := b.db.Exec(c, query, uuid.NewString(), req.MuterId, req.MutedId)
+	if err != nil {
+		return fmt.Errorf("failed to mute user: %w", err)
+	}
+
+	return nil
+}
+
+func (b *blockRepo) Unmute(c context.Context, req *models.Mute) error {
+	query := `DELETE FROM "user_mutes" WHERE "muter_id" = $1 AND "muted_id" = $2`
+
+	result, err := b.db.Exec(c, query, req.MuterId, req.MutedId)
+	if err != nil {
+		return fmt.Errorf("failed to unmute user: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return storage.ErrNotMuted
+	}
+
+	return nil
+}
+
+// GetMutes lists the users req.UserId has muted, newest first
+func (b *blockRepo) GetMutes(c context.Context, req *models.GetBlocksRequest) (*models.GetAllBlocks, error) {
+	return b.listUsers(c, req, "user_mutes", "muter_id", "muted_id")
+}
+
+// listUsers lists the active users in column listed of table where column
+// owner is req.UserId
+func (b *blockRepo) listUsers(c context.Context, req *models.GetBlocksRequest, table, owner, listed string) (*models.GetAllBlocks, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			COUNT(*) OVER(),
+			u."id",
+			u."username",
+			COALESCE(u."display_name", ''),
+			COALESCE(u."avatar_url", ''),
+			u."is_verified",
+			t."created_at"
+		FROM "%s" t
+		JOIN "users" u ON u."id" = t."%s"
+		WHERE
+			u."is_active" = true AND
+			t."%s" = $1
+		ORDER BY t."created_at" DESC
+		OFFSET $2 LIMIT $3
+	`, table, listed, owner)
+
+	rows, err := b.db.Query(c, query, req.UserId, (req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	resp := &models.GetAllBlocks{Users: make([]models.BlockedUser, 0)}
+	for rows.Next() {
+		var created_at time.Time
+
+		user := models.BlockedUser{}
+		err := rows.Scan(
+			&resp.Count,
+			&user.ID,
+			&user.Username,
+			&user.DisplayName,
+			&user.AvatarURL,
+			&user.IsVerified,
+			&created_at,
+		)
+		if err != nil {
+			return nil, err
+		}
+		user.CreatedAt = created_at.Format(time.RFC3339)
+
+		resp.Users = append(resp.Users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// blockedAuthor returns storage.ErrBlocked when the user and the author of the
+// row id in table have blocked each other
+func blockedAuthor(c context.Context, db *pgxpool.Pool, userId, table, id string) error {
+	query := fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM "%s" t
+			JOIN "user_blocks" ub ON
+				(ub."blocker_id" = $1 AND ub."blocked_id" = t."created_by") OR
+				(ub."blocker_id" = t."created_by" AND ub."blocked_id" = $1)
+			WHERE t."id" = $2
+		)
+	`, table)
+
+	blocked := false
+	if err := db.QueryRow(c, query, userId, id).Scan(&blocked); err != nil {
+		return fmt.Errorf("failed to check block: %w", err)
+	}
+	if blocked {
+		return storage.ErrBlocked
+	}
+
+	return nil
+}
@@ -4,8 +4,10 @@ import (
 	"auth/models"
 	"auth/pkg/helper"
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx"
@@ -25,6 +27,13 @@ func NewCommentLikeRepo(db *pgxpool.Pool) *commentLikeRepo {
 func (b *commentLikeRepo) AddLike(c context.Context, req *models.CreateCommentLike) error {
 	userInfo := c.Value("user_info").(helper.TokenInfo)
 
+	if err := blockedAuthor(c, b.db, userInfo.User_id, "post_comments", req.CommentId); err != nil {
+		return err
+	}
+	if err := hiddenPost(c, b.db, userInfo.User_id, "post_comments", req.CommentId); err != nil {
+		return err
+	}
+
 	// Check if the user has already liked the post
 	query := `
 		SELECT COUNT(id) FROM comment_likes WHERE comment_id = $1 AND user_id = $2
@@ -97,3 +106,44 @@ func (b *commentLikeRepo) GetLikesCount(c context.Context, req string) (int, err
 
 	return count, nil
 }
+
+// GetUserLikes returns every comment like the user made, removed ones included
+func (b *commentLikeRepo) GetUserLikes(c context.Context, userId string) ([]models.CommentLike, error) {
+	query := `
+		SELECT
+			"id",
+			"comment_id",
+			"created_at",
+			"deleted_at"
+		FROM "comment_likes"
+		WHERE "user_id" = $1
+		ORDER BY "created_at" DESC
+	`
+
+	rows, err := b.db.Query(c, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment likes: %w", err)
+	}
+	defer rows.Close()
+
+	likes := make([]models.CommentLike, 0)
+	for rows.Next() {
+		var (
+			created_at time.Time
+			deleted_at sql.NullTime
+		)
+
+		like := models.CommentLike{UserId: userId}
+		if err := rows.Scan(&like.Id, &like.CommentId, &created_at, &deleted_at); err != nil {
+			return nil, err
+		}
+		like.CreatedAt = created_at.Format(time.RFC3339)
+		if deleted_at.Valid {
+			like.DeletedAt = deleted_at.Time.Format(time.RFC3339)
+		}
+
+		likes = append(likes, like)
+	}
+
+	return likes, rows.Err()
+}
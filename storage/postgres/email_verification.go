@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"auth/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type emailVerificationRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewEmailVerificationRepo(db *pgxpool.Pool) *emailVerificationRepo {
+	return &emailVerificationRepo{
+		db: db,
+	}
+}
+
+// CreateEmailVerification stores a new verification token and invalidates
+// the ones sent before, so only the latest link works
+func (b *emailVerificationRepo) CreateEmailVerification(c context.Context, req *models.CreateEmailVerification) (string, error) {
+	id := uuid.NewString()
+
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	_, err = tx.Exec(c, `
+		UPDATE "email_verification_tokens"
+		SET
+			"used_at" = NOW()
+		WHERE
+			"used_at" IS NULL AND
+			"user_id" = $1
+	`, req.UserId)
+	if err != nil {
+		return "", fmt.Errorf("failed to invalidate email verification tokens: %w", err)
+	}
+
+	_, err = tx.Exec(c, `
+		INSERT INTO "email_verification_tokens"(
+			"id",
+			"user_id",
+			"token_hash",
+			"expires_at",
+			"created_at")
+		VALUES ($1, $2, $3, $4, NOW())
+	`, id, req.UserId, req.TokenHash, req.ExpiresAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to create email verification token: %w", err)
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return id, nil
+}
+
+// VerifyEmail consumes the token and marks the user's email as verified
+func (b *emailVerificationRepo) VerifyEmail(c context.Context, tokenHash string) (string, error) {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	var userId string
+	err = tx.QueryRow(c, `
+		UPDATE "email_verification_tokens"
+		SET
+			"used_at" = NOW()
+		WHERE
+			"used_at" IS NULL AND
+			"expires_at" > NOW() AND
+			"token_hash" = $1
+		RETURNING "user_id"
+	`, tokenHash).Scan(&userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("email verification token is invalid or expired")
+		}
+		return "", fmt.Errorf("failed to use email verification token: %w", err)
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE "users"
+		SET
+			"email_verified_at" = NOW()
+		WHERE
+			"email_verified_at" IS NULL AND
+			"id" = $1
+	`, userId)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return userId, nil
+}
@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"auth/models"
+	"auth/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type exportRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewExportRepo(db *pgxpool.Pool) *exportRepo {
+	return &exportRepo{
+		db: db,
+	}
+}
+
+// CreateExport starts a pending export for the user
+func (b *exportRepo) CreateExport(c context.Context, userId string) (string, error) {
+	id := uuid.NewString()
+
+	query := `
+		INSERT INTO "data_exports" ("id", "user_id", "status", "created_at")
+		VALUES ($1, $2, $3, NOW())
+	`
+	_, err := b.db.Exec(c, query, id, userId, models.ExportPending)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return "", storage.ErrExportPending
+		}
+		return "", fmt.Errorf("failed to create export: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetExport returns an export of the user. A ready export past its expiry
+// time is reported as expired even before the cleanup job removes it.
+func (b *exportRepo) GetExport(c context.Context, req *models.GetExport) (*models.DataExport, error) {
+	query := `
+		SELECT
+			"id",
+			"user_id",
+			CASE
+				WHEN "status" = $3 AND "expires_at" <= NOW() THEN $4
+				ELSE "status"
+			END,
+			COALESCE("file_path", ''),
+			COALESCE("error", ''),
+			"expires_at",
+			"completed_at",
+			"created_at"
+		FROM "data_exports"
+		WHERE
+			"id" = $1 AND
+			"user_id" = $2
+	`
+
+	var (
+		expires_at   sql.NullTime
+		completed_at sql.NullTime
+		created_at   time.Time
+	)
+
+	export := models.DataExport{}
+	err := b.db.QueryRow(c, query, req.Id, req.UserId, models.ExportReady, models.ExportExpired).Scan(
+		&export.ID,
+		&export.UserId,
+		&export.Status,
+		&export.FilePath,
+		&export.Error,
+		&expires_at,
+		&completed_at,
+		&created_at,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrExportNotFound
+		}
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+
+	export.CreatedAt = created_at.Format(time.RFC3339)
+	if expires_at.Valid {
+		export.ExpiresAt = expires_at.Time.Format(time.RFC3339)
+	}
+	if completed_at.Valid {
+		export.CompletedAt = completed_at.Time.Format(time.RFC3339)
+	}
+
+	return &export, nil
+}
+
+func (b *exportRepo) CompleteExport(c context.Context, req *models.CompleteExport) error {
+	query := `
+		UPDATE "data_exports"
+		SET
+			"status" = $2,
+			"file_path" = $3,
+			"expires_at" = $4,
+			"completed_at" = NOW()
+		WHERE
+			"status" = $5 AND
+			"id" = $1
+	`
+
+	result, err := b.db.Exec(c, query, req.Id, models.ExportReady, req.FilePath, req.ExpiresAt, models.ExportPending)
+	if err != nil {
+		return fmt.Errorf("failed to complete export: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return storage.ErrExportNotFound
+	}
+
+	return nil
+}
+
+func (b *exportRepo) FailExport(c context.Context, req *models.FailExport) error {
+	query := `
+		UPDATE "data_exports"
+		SET
+			"status" = $2,
+			"error" = $3,
+			"completed_at" = NOW()
+		WHERE
+			"status" = $4 AND
+			"id" = $1
+	`
+
+	_, err := b.db.Exec(c, query, req.Id, models.ExportFailed, req.Error, models.ExportPending)
+	if err != nil {
+		return fmt.Errorf("failed to fail export: %w", err)
+	}
+
+	return nil
+}
+
+// ExpireExports marks ready exports past their expiry time as expired and
+// returns the archives to remove. Pending exports started before staleBefore
+// are marked as failed, their job didn't finish.
+func (b *exportRepo) ExpireExports(c context.Context, staleBefore time.Time) ([]string, error) {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	query := `
+		UPDATE "data_exports"
+		SET
+			"status" = $2,
+			"error" = 'export timed out',
+			"completed_at" = NOW()
+		WHERE
+			"status" = $3 AND
+			"created_at" < $1
+	`
+	if _, err = tx.Exec(c, query, staleBefore, models.ExportFailed, models.ExportPending); err != nil {
+		return nil, fmt.Errorf("failed to fail stale exports: %w", err)
+	}
+
+	query = `
+		UPDATE "data_exports"
+		SET
+			"status" = $1
+		WHERE
+			"status" = $2 AND
+			"expires_at" <= NOW()
+		RETURNING COALESCE("file_path", '')
+	`
+	rows, err := tx.Query(c, query, models.ExportExpired, models.ExportReady)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire exports: %w", err)
+	}
+	defer rows.Close()
+
+	paths := make([]string, 0)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return paths, nil
+}
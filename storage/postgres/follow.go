@@ -0,0 +1,340 @@
+package postgres
+
+import (
+	"auth/models"
+	"auth/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type followRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewFollowRepo(db *pgxpool.Pool) *followRepo {
+	return &followRepo{
+		db: db,
+	}
+}
+
+// Follow starts following a user and reports whether a new follow was made,
+// following the same user twice does nothing
+func (b *followRepo) Follow(c context.Context, req *models.Follow) (bool, error) {
+	query := `
+		INSERT INTO "follows" ("id", "follower_id", "following_id", "created_at")
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT ("follower_id", "following_id") WHERE "deleted_at" IS NULL DO NOTHING
+	`
+
+	result, err := b.db.Exec(c, query, uuid.NewString(), req.FollowerId, req.FollowingId)
+	if err != nil {
+		return false, fmt.Errorf("failed to follow user: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+func (b *followRepo) Unfollow(c context.Context, req *models.Follow) error {
+	query := `
+		UPDATE "follows"
+		SET
+			"deleted_at" = NOW()
+		WHERE
+			"deleted_at" IS NULL AND
+			"follower_id" = $1 AND
+			"following_id" = $2
+	`
+
+	result, err := b.db.Exec(c, query, req.FollowerId, req.FollowingId)
+	if err != nil {
+		return fmt.Errorf("failed to unfollow user: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return storage.ErrNotFollowing
+	}
+
+	return nil
+}
+
+// IsMutual reports whether the two users follow each other
+func (b *followRepo) IsMutual(c context.Context, userId, otherId string) (bool, error) {
+	query := `
+		SELECT COUNT(*) = 2
+		FROM "follows"
+		WHERE
+			"deleted_at" IS NULL AND (
+				("follower_id" = $1 AND "following_id" = $2) OR
+				("follower_id" = $2 AND "following_id" = $1)
+			)
+	`
+
+	mutual := false
+	err := b.db.QueryRow(c, query, userId, otherId).Scan(&mutual)
+	if err != nil {
+		return false, fmt.Errorf("failed to check mutual follow: %w", err)
+	}
+
+	return mutual, nil
+}
+
+// IsFollowing reports whether the user follows the other one
+func (b *followRepo) IsFollowing(c context.Context, userId, otherId string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM "follows"
+			WHERE
+				"deleted_at" IS NULL AND
+				"follower_id" = $1 AND
+				"following_id" = $2
+		)
+	`
+
+	following := false
+	err := b.db.QueryRow(c, query, userId, otherId).Scan(&following)
+	if err != nil {
+		return false, fmt.Errorf("failed to check follow: %w", err)
+	}
+
+	return following, nil
+}
+
+// RequestFollow asks a private account to be followed and reports whether a
+// new request was made, asking twice does nothing
+func (b *followRepo) RequestFollow(c context.Context, req *models.Follow) (bool, error) {
+	query := `
+		INSERT INTO "follow_requests" ("id", "requester_id", "target_id", "created_at")
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT ("requester_id", "target_id") DO NOTHING
+	`
+
+	result, err := b.db.Exec(c, query, uuid.NewString(), req.FollowerId, req.FollowingId)
+	if err != nil {
+		return false, fmt.Errorf("failed to request follow: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// CancelFollowRequest withdraws a pending request of req.FollowerId
+func (b *followRepo) CancelFollowRequest(c context.Context, req *models.Follow) error {
+	query := `DELETE FROM "follow_requests" WHERE "requester_id" = $1 AND "target_id" = $2`
+
+	result, err := b.db.Exec(c, query, req.FollowerId, req.FollowingId)
+	if err != nil {
+		return fmt.Errorf("failed to cancel follow request: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return storage.ErrFollowRequestNotFound
+	}
+
+	return nil
+}
+
+// GetFollowRequests lists the pending requests sent to req.UserId by active
+// users, oldest first
+func (b *followRepo) GetFollowRequests(c context.Context, req *models.GetFollowsRequest) (*models.GetAllFollowRequests, error) {
+	query := `
+		SELECT
+			COUNT(*) OVER(),
+			fr."id",
+			u."id",
+			u."username",
+			COALESCE(u."display_name", ''),
+			COALESCE(u."avatar_url", ''),
+			u."is_verified",
+			fr."created_at"
+		FROM "follow_requests" fr
+		JOIN "users" u ON u."id" = fr."requester_id"
+		WHERE
+			u."is_active" = true AND
+			fr."target_id" = $1
+		ORDER BY fr."created_at"
+		OFFSET $2 LIMIT $3
+	`
+
+	rows, err := b.db.Query(c, query, req.UserId, (req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	resp := &models.GetAllFollowRequests{Requests: make([]models.FollowRequest, 0)}
+	for rows.Next() {
+		var created_at time.Time
+
+		request := models.FollowRequest{}
+		err := rows.Scan(
+			&resp.Count,
+			&request.ID,
+			&request.UserId,
+			&request.Username,
+			&request.DisplayName,
+			&request.AvatarURL,
+			&request.IsVerified,
+			&created_at,
+		)
+		if err != nil {
+			return nil, err
+		}
+		request.RequestedAt = created_at.Format(time.RFC3339)
+
+		resp.Requests = append(resp.Requests, request)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ApproveFollowRequest turns a pending request sent to req.UserId into a follow
+func (b *followRepo) ApproveFollowRequest(c context.Context, req *models.ReviewFollowRequest) error {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	query := `
+		DELETE FROM "follow_requests"
+		WHERE
+			"id" = $1 AND
+			"target_id" = $2
+		RETURNING "requester_id"
+	`
+
+	var requesterId string
+	err = tx.QueryRow(c, query, req.Id, req.UserId).Scan(&requesterId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrFollowRequestNotFound
+		}
+		return fmt.Errorf("failed to approve follow request: %w", err)
+	}
+
+	if err = insertFollow(c, tx, requesterId, req.UserId); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RejectFollowRequest drops a pending request sent to req.UserId
+func (b *followRepo) RejectFollowRequest(c context.Context, req *models.ReviewFollowRequest) error {
+	query := `DELETE FROM "follow_requests" WHERE "id" = $1 AND "target_id" = $2`
+
+	result, err := b.db.Exec(c, query, req.Id, req.UserId)
+	if err != nil {
+		return fmt.Errorf("failed to reject follow request: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return storage.ErrFollowRequestNotFound
+	}
+
+	return nil
+}
+
+// insertFollow makes followerId follow followingId inside tx, an existing
+// follow is kept
+func insertFollow(c context.Context, tx pgx.Tx, followerId, followingId string) error {
+	query := `
+		INSERT INTO "follows" ("id", "follower_id", "following_id", "created_at")
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT ("follower_id", "following_id") WHERE "deleted_at" IS NULL DO NOTHING
+	`
+
+	if _, err := tx.Exec(c, query, uuid.NewString(), followerId, followingId); err != nil {
+		return fmt.Errorf("failed to follow user: %w", err)
+	}
+
+	return nil
+}
+
+// GetFollowers lists the active users following req.UserId, newest first
+func (b *followRepo) GetFollowers(c context.Context, req *models.GetFollowsRequest) (*models.GetAllFollows, error) {
+	return b.getFollows(c, req, "follower_id", "following_id")
+}
+
+// GetFollowing lists the active users req.UserId follows, newest first
+func (b *followRepo) GetFollowing(c context.Context, req *models.GetFollowsRequest) (*models.GetAllFollows, error) {
+	return b.getFollows(c, req, "following_id", "follower_id")
+}
+
+// getFollows lists the users in column listed of the follows where column
+// owner is req.UserId. A user is mutual when the reverse follow exists too.
+func (b *followRepo) getFollows(c context.Context, req *models.GetFollowsRequest, listed, owner string) (*models.GetAllFollows, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			COUNT(*) OVER(),
+			u."id",
+			u."username",
+			COALESCE(u."display_name", ''),
+			COALESCE(u."avatar_url", ''),
+			u."is_verified",
+			EXISTS (
+				SELECT 1
+				FROM "follows" r
+				WHERE
+					r."deleted_at" IS NULL AND
+					r."%[2]s" = f."%[1]s" AND
+					r."%[1]s" = f."%[2]s"
+			),
+			f."created_at"
+		FROM "follows" f
+		JOIN "users" u ON u."id" = f."%[1]s"
+		WHERE
+			f."deleted_at" IS NULL AND
+			u."is_active" = true AND
+			f."%[2]s" = $1
+		ORDER BY f."created_at" DESC
+		OFFSET $2 LIMIT $3
+	`, listed, owner)
+
+	rows, err := b.db.Query(c, query, req.UserId, (req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	resp := &models.GetAllFollows{Users: make([]models.FollowUser, 0)}
+	for rows.Next() {
+		var created_at time.Time
+
+		user := models.FollowUser{}
+		err := rows.Scan(
+			&resp.Count,
+			&user.ID,
+			&user.Username,
+			&user.DisplayName,
+			&user.AvatarURL,
+			&user.IsVerified,
+			&user.IsMutual,
+			&created_at,
+		)
+		if err != nil {
+			return nil, err
+		}
+		user.FollowedAt = created_at.Format(time.RFC3339)
+
+		resp.Users = append(resp.Users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,192 @@
+package postgres
+
+import (
+	"auth/models"
+	"auth/storage"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type identityRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewIdentityRepo(db *pgxpool.Pool) *identityRepo {
+	return &identityRepo{
+		db: db,
+	}
+}
+
+func (b *identityRepo) CreateOIDCState(c context.Context, req *models.CreateOIDCState) error {
+	query := `
+		INSERT INTO "oidc_login_states"(
+			"id",
+			"provider",
+			"state_hash",
+			"nonce",
+			"code_verifier",
+			"expires_at",
+			"created_at")
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`
+	_, err := b.db.Exec(c, query,
+		uuid.NewString(),
+		req.Provider,
+		req.StateHash,
+		req.Nonce,
+		req.CodeVerifier,
+		req.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create oidc state: %w", err)
+	}
+
+	return nil
+}
+
+// UseOIDCState marks the state as used and returns it, so a callback can't be replayed
+func (b *identityRepo) UseOIDCState(c context.Context, stateHash string) (*models.OIDCState, error) {
+	query := `
+		UPDATE "oidc_login_states"
+		SET
+			"used_at" = NOW()
+		WHERE
+			"used_at" IS NULL AND
+			"expires_at" > NOW() AND
+			"state_hash" = $1
+		RETURNING "provider", "nonce", "code_verifier"
+	`
+
+	state := models.OIDCState{}
+	err := b.db.QueryRow(c, query, stateHash).Scan(
+		&state.Provider,
+		&state.Nonce,
+		&state.CodeVerifier,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("oidc state is invalid or expired")
+		}
+		return nil, fmt.Errorf("failed to use oidc state: %w", err)
+	}
+
+	return &state, nil
+}
+
+func (b *identityRepo) GetUserByIdentity(c context.Context, provider, subject string) (*models.LoginDataRespond, error) {
+	query := `
+		SELECT
+			u."id",
+			u."username",
+			u."role",
+			COALESCE(u."email", ''),
+			u."email_verified_at" IS NOT NULL,
+			u."phone_verified_at" IS NOT NULL,
+			u."totp_enabled",
+			u."is_active" = false
+		FROM "user_identities" i
+		JOIN "users" u ON u."id" = i."user_id"
+		WHERE
+			i."provider" = $1 AND
+			i."subject" = $2 AND ` + canLogInFilter("u.")
+
+	user := models.LoginDataRespond{}
+	err := b.db.QueryRow(c, query, provider, subject).Scan(
+		&user.User_id,
+		&user.Username,
+		&user.Role,
+		&user.Email,
+		&user.EmailVerified,
+		&user.PhoneVerified,
+		&user.TwoFactorEnabled,
+		&user.DeletionScheduled,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by identity: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (b *identityRepo) LinkIdentity(c context.Context, req *models.LinkIdentity) error {
+	query := `
+		INSERT INTO "user_identities"(
+			"id",
+			"user_id",
+			"provider",
+			"subject",
+			"email",
+			"created_at")
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NOW())
+	`
+	_, err := b.db.Exec(c, query,
+		uuid.NewString(),
+		req.UserId,
+		req.Provider,
+		req.Subject,
+		req.Email,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	return nil
+}
+
+// CreateIdentityUser creates the user together with its first identity. The
+// email is only passed when the provider verified it, and a user without one is
+// vouched for by the provider, so the account starts out verified either way.
+func (b *identityRepo) CreateIdentityUser(c context.Context, req *models.CreateIdentityUser) (string, error) {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	id := uuid.NewString()
+
+	_, err = tx.Exec(c, `
+		INSERT INTO "users"(
+			"id",
+			"username",
+			"email",
+			"email_verified_at",
+			"password",
+			"created_at")
+		VALUES ($1, $2, LOWER(NULLIF($3, '')), NOW(), $4, NOW())
+	`, id, req.Username, req.Email, req.Password)
+	if err != nil {
+		if taken := uniqueUserViolation(err); taken != nil {
+			return "", taken
+		}
+		return "", fmt.Errorf("failed to create user: %w", err)
+	}
+
+	_, err = tx.Exec(c, `
+		INSERT INTO "user_identities"(
+			"id",
+			"user_id",
+			"provider",
+			"subject",
+			"email",
+			"created_at")
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NOW())
+	`, uuid.NewString(), id, req.Provider, req.Subject, req.Email)
+	if err != nil {
+		return "", fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"auth/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type loginFailureRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginFailureRepo(db *pgxpool.Pool) *loginFailureRepo {
+	return &loginFailureRepo{
+		db: db,
+	}
+}
+
+// GetLoginFailure returns an empty record if the key has no failures
+func (b *loginFailureRepo) GetLoginFailure(c context.Context, key string) (*models.LoginFailure, error) {
+	var locked_until sql.NullTime
+
+	query := `
+		SELECT
+			"key",
+			"failed_count",
+			"last_failed_at",
+			"locked_until"
+		FROM "login_failures"
+		WHERE "key" = $1
+	`
+
+	failure := models.LoginFailure{Key: key}
+	err := b.db.QueryRow(c, query, key).Scan(
+		&failure.Key,
+		&failure.FailedCount,
+		&failure.LastFailedAt,
+		&locked_until,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &failure, nil
+		}
+		return nil, fmt.Errorf("failed to get login failures: %w", err)
+	}
+	failure.LockedUntil = locked_until.Time
+
+	return &failure, nil
+}
+
+// RegisterLoginFailure counts one more failure for the key. The counter starts over
+// when the last failure is older than the window or a previous lock has expired,
+// and the key gets locked once the counter reaches MaxFailures.
+func (b *loginFailureRepo) RegisterLoginFailure(c context.Context, req *models.RegisterLoginFailure) (*models.LoginFailure, error) {
+	var locked_until sql.NullTime
+
+	query := `
+		INSERT INTO "login_failures" AS lf ("key", "failed_count", "last_failed_at", "locked_until")
+		VALUES (
+			$1,
+			1,
+			NOW(),
+			CASE WHEN $3 <= 1 THEN NOW() + $4 * INTERVAL '1 second' END
+		)
+		ON CONFLICT ("key") DO UPDATE
+		SET
+			"failed_count" = CASE
+				WHEN lf."last_failed_at" < NOW() - $2 * INTERVAL '1 second' OR lf."locked_until" < NOW()
+				THEN 1
+				ELSE lf."failed_count" + 1
+			END,
+			"last_failed_at" = NOW(),
+			"locked_until" = CASE
+				WHEN lf."locked_until" >= NOW()
+				THEN lf."locked_until"
+				WHEN lf."last_failed_at" >= NOW() - $2 * INTERVAL '1 second'
+					AND lf."locked_until" IS NULL
+					AND lf."failed_count" + 1 >= $3
+				THEN NOW() + $4 * INTERVAL '1 second'
+			END
+		RETURNING "key", "failed_count", "last_failed_at", "locked_until"
+	`
+
+	failure := models.LoginFailure{}
+	err := b.db.QueryRow(c, query,
+		req.Key,
+		int(req.Window.Seconds()),
+		req.MaxFailures,
+		int(req.LockDuration.Seconds()),
+	).Scan(
+		&failure.Key,
+		&failure.FailedCount,
+		&failure.LastFailedAt,
+		&locked_until,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register login failure: %w", err)
+	}
+	failure.LockedUntil = locked_until.Time
+
+	return &failure, nil
+}
+
+func (b *loginFailureRepo) ResetLoginFailures(c context.Context, key string) error {
+	_, err := b.db.Exec(c, `DELETE FROM "login_failures" WHERE "key" = $1`, key)
+	if err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+
+	return nil
+}
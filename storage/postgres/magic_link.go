@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"auth/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type magicLinkRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewMagicLinkRepo(db *pgxpool.Pool) *magicLinkRepo {
+	return &magicLinkRepo{
+		db: db,
+	}
+}
+
+// CountMagicLinks returns how many links were sent to the email since the given time
+func (b *magicLinkRepo) CountMagicLinks(c context.Context, email string, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM "magic_link_tokens"
+		WHERE
+			LOWER("email") = LOWER($1) AND
+			"created_at" > $2
+	`
+
+	count := 0
+	err := b.db.QueryRow(c, query, email, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count magic links: %w", err)
+	}
+
+	return count, nil
+}
+
+func (b *magicLinkRepo) CreateMagicLink(c context.Context, req *models.CreateMagicLink) error {
+	query := `
+		INSERT INTO "magic_link_tokens"(
+			"id",
+			"user_id",
+			"email",
+			"token_hash",
+			"device_hash",
+			"expires_at",
+			"created_at")
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`
+	_, err := b.db.Exec(c, query,
+		uuid.NewString(),
+		req.UserId,
+		req.Email,
+		req.TokenHash,
+		req.DeviceHash,
+		req.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create magic link: %w", err)
+	}
+
+	return nil
+}
+
+// UseMagicLink consumes the token if it was requested from the same device and
+// returns its user. Following the link proves the email, so it is marked verified.
+func (b *magicLinkRepo) UseMagicLink(c context.Context, req *models.UseMagicLink) (*models.LoginDataRespond, error) {
+	query := `
+		WITH used AS (
+			UPDATE "magic_link_tokens"
+			SET
+				"used_at" = NOW()
+			WHERE
+				"used_at" IS NULL AND
+				"expires_at" > NOW() AND
+				"token_hash" = $1 AND
+				"device_hash" = $2
+			RETURNING "user_id"
+		)
+		UPDATE "users"
+		SET
+			"email_verified_at" = COALESCE("email_verified_at", NOW())
+		WHERE
+			"id" IN (SELECT "user_id" FROM used) AND ` + canLogInFilter("") + `
+		RETURNING
+			"id",
+			"username",
+			"role",
+			COALESCE("email", ''),
+			"phone_verified_at" IS NOT NULL,
+			"totp_enabled",
+			"is_active" = false
+	`
+
+	user := models.LoginDataRespond{EmailVerified: true}
+	err := b.db.QueryRow(c, query, req.TokenHash, req.DeviceHash).Scan(
+		&user.User_id,
+		&user.Username,
+		&user.Role,
+		&user.Email,
+		&user.PhoneVerified,
+		&user.TwoFactorEnabled,
+		&user.DeletionScheduled,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("magic link is invalid or expired")
+		}
+		return nil, fmt.Errorf("failed to use magic link: %w", err)
+	}
+
+	return &user, nil
+}
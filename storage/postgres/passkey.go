@@ -0,0 +1,278 @@
+package postgres
+
+import (
+	"auth/models"
+	"auth/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type passkeyRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPasskeyRepo(db *pgxpool.Pool) *passkeyRepo {
+	return &passkeyRepo{
+		db: db,
+	}
+}
+
+func (b *passkeyRepo) CreateWebAuthnChallenge(c context.Context, req *models.CreateWebAuthnChallenge) error {
+	query := `
+		INSERT INTO "webauthn_challenges"(
+			"id",
+			"user_id",
+			"ceremony",
+			"challenge_hash",
+			"expires_at",
+			"created_at")
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, NOW())
+	`
+	_, err := b.db.Exec(c, query,
+		uuid.NewString(),
+		req.UserId,
+		req.Ceremony,
+		req.ChallengeHash,
+		req.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webauthn challenge: %w", err)
+	}
+
+	return nil
+}
+
+// UseWebAuthnChallenge marks the challenge as used and returns the user it was
+// handed out to, empty for logins. A challenge can be answered only once.
+func (b *passkeyRepo) UseWebAuthnChallenge(c context.Context, challengeHash, ceremony string) (string, error) {
+	query := `
+		UPDATE "webauthn_challenges"
+		SET
+			"used_at" = NOW()
+		WHERE
+			"used_at" IS NULL AND
+			"expires_at" > NOW() AND
+			"ceremony" = $2 AND
+			"challenge_hash" = $1
+		RETURNING "user_id"
+	`
+
+	var userId sql.NullString
+	err := b.db.QueryRow(c, query, challengeHash, ceremony).Scan(&userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("webauthn challenge is invalid or expired")
+		}
+		return "", fmt.Errorf("failed to use webauthn challenge: %w", err)
+	}
+
+	return userId.String, nil
+}
+
+func (b *passkeyRepo) CreatePasskey(c context.Context, req *models.CreatePasskey) (string, error) {
+	id := uuid.NewString()
+
+	query := `
+		INSERT INTO "webauthn_credentials"(
+			"id",
+			"user_id",
+			"credential_id",
+			"public_key",
+			"sign_count",
+			"name",
+			"created_at")
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`
+	_, err := b.db.Exec(c, query,
+		id,
+		req.UserId,
+		req.CredentialId,
+		req.PublicKey,
+		req.SignCount,
+		req.Name,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return "", storage.ErrPasskeyExists
+		}
+		return "", fmt.Errorf("failed to create passkey: %w", err)
+	}
+
+	return id, nil
+}
+
+func (b *passkeyRepo) GetMyPasskeys(c context.Context, userId string) (*models.GetAllPasskeys, error) {
+	query := `
+		SELECT
+			"id",
+			"name",
+			"last_used_at",
+			"created_at"
+		FROM "webauthn_credentials"
+		WHERE "user_id" = $1
+		ORDER BY "created_at" DESC
+	`
+
+	rows, err := b.db.Query(c, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passkeys := make([]models.Passkey, 0)
+	for rows.Next() {
+		var (
+			last_used_at sql.NullTime
+			created_at   time.Time
+		)
+
+		passkey := models.Passkey{}
+		err := rows.Scan(
+			&passkey.ID,
+			&passkey.Name,
+			&last_used_at,
+			&created_at,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		passkey.CreatedAt = created_at.Format(time.RFC3339)
+		if last_used_at.Valid {
+			passkey.LastUsedAt = last_used_at.Time.Format(time.RFC3339)
+		}
+
+		passkeys = append(passkeys, passkey)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.GetAllPasskeys{Passkeys: passkeys, Count: len(passkeys)}, nil
+}
+
+// GetPasskeyCredentialIds returns the raw credential ids of the user, so the
+// browser doesn't register the same authenticator twice
+func (b *passkeyRepo) GetPasskeyCredentialIds(c context.Context, userId string) ([][]byte, error) {
+	rows, err := b.db.Query(c, `SELECT "credential_id" FROM "webauthn_credentials" WHERE "user_id" = $1`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([][]byte, 0)
+	for rows.Next() {
+		var id []byte
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (b *passkeyRepo) GetPasskeyByCredentialId(c context.Context, credentialId []byte) (*models.PasskeyCredential, error) {
+	query := `
+		SELECT
+			"id",
+			"user_id",
+			"public_key",
+			"sign_count"
+		FROM "webauthn_credentials"
+		WHERE "credential_id" = $1
+	`
+
+	credential := models.PasskeyCredential{}
+	err := b.db.QueryRow(c, query, credentialId).Scan(
+		&credential.ID,
+		&credential.UserId,
+		&credential.PublicKey,
+		&credential.SignCount,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrPasskeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get passkey: %w", err)
+	}
+
+	return &credential, nil
+}
+
+// UsePasskey stores the new signature counter and returns the user the passkey
+// belongs to, if they may log in
+func (b *passkeyRepo) UsePasskey(c context.Context, req *models.UsePasskey) (*models.LoginDataRespond, error) {
+	query := `
+		WITH used AS (
+			UPDATE "webauthn_credentials"
+			SET
+				"sign_count" = $3,
+				"last_used_at" = NOW()
+			WHERE
+				"sign_count" = $2 AND
+				"id" = $1
+			RETURNING "user_id"
+		)
+		SELECT
+			u."id",
+			u."username",
+			u."role",
+			COALESCE(u."email", ''),
+			u."email_verified_at" IS NOT NULL,
+			u."phone_verified_at" IS NOT NULL,
+			u."totp_enabled",
+			u."is_active" = false
+		FROM "users" u
+		JOIN used ON used."user_id" = u."id"
+		WHERE ` + canLogInFilter("u.")
+
+	user := models.LoginDataRespond{}
+	err := b.db.QueryRow(c, query, req.Id, req.OldSignCount, req.SignCount).Scan(
+		&user.User_id,
+		&user.Username,
+		&user.Role,
+		&user.Email,
+		&user.EmailVerified,
+		&user.PhoneVerified,
+		&user.TwoFactorEnabled,
+		&user.DeletionScheduled,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrPasskeyNotFound
+		}
+		return nil, fmt.Errorf("failed to use passkey: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (b *passkeyRepo) DeletePasskey(c context.Context, req *models.DeletePasskey) (string, error) {
+	query := `
+		DELETE FROM "webauthn_credentials"
+		WHERE
+			"user_id" = $1 AND
+			"id" = $2
+	`
+
+	result, err := b.db.Exec(c, query, req.UserId, req.Id)
+	if err != nil {
+		return "", fmt.Errorf("failed to delete passkey: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return "", storage.ErrPasskeyNotFound
+	}
+
+	return req.Id, nil
+}
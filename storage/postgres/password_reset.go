@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"auth/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type passwordResetRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPasswordResetRepo(db *pgxpool.Pool) *passwordResetRepo {
+	return &passwordResetRepo{
+		db: db,
+	}
+}
+
+// CountPasswordResets returns how many reset links the user was sent since the given time
+func (b *passwordResetRepo) CountPasswordResets(c context.Context, userId string, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM "password_reset_tokens"
+		WHERE
+			"user_id" = $1 AND
+			"created_at" > $2
+	`
+
+	count := 0
+	err := b.db.QueryRow(c, query, userId, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count password reset tokens: %w", err)
+	}
+
+	return count, nil
+}
+
+// CreatePasswordReset stores a new reset token and invalidates the ones
+// the user requested before
+func (b *passwordResetRepo) CreatePasswordReset(c context.Context, req *models.CreatePasswordReset) (string, error) {
+	id := uuid.NewString()
+
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	_, err = tx.Exec(c, `
+		UPDATE "password_reset_tokens"
+		SET
+			"used_at" = NOW()
+		WHERE
+			"used_at" IS NULL AND
+			"user_id" = $1
+	`, req.UserId)
+	if err != nil {
+		return "", fmt.Errorf("failed to invalidate password reset tokens: %w", err)
+	}
+
+	_, err = tx.Exec(c, `
+		INSERT INTO "password_reset_tokens"(
+			"id",
+			"user_id",
+			"token_hash",
+			"expires_at",
+			"created_at")
+		VALUES ($1, $2, $3, $4, NOW())
+	`, id, req.UserId, req.TokenHash, req.ExpiresAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return id, nil
+}
+
+// ResetPassword consumes the reset token, sets the new password and revokes
+// every session of the user
+func (b *passwordResetRepo) ResetPassword(c context.Context, req *models.ResetPassword) (string, error) {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	var userId string
+	err = tx.QueryRow(c, `
+		UPDATE "password_reset_tokens"
+		SET
+			"used_at" = NOW()
+		WHERE
+			"used_at" IS NULL AND
+			"expires_at" > NOW() AND
+			"token_hash" = $1
+		RETURNING "user_id"
+	`, req.TokenHash).Scan(&userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("password reset token is invalid or expired")
+		}
+		return "", fmt.Errorf("failed to use password reset token: %w", err)
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE "users"
+		SET
+			"password" = $1,
+			"updated_at" = NOW()
+		WHERE "id" = $2
+	`, req.Password, userId)
+	if err != nil {
+		return "", fmt.Errorf("failed to update password: %w", err)
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE "sessions"
+		SET
+			"revoked_at" = NOW()
+		WHERE
+			"revoked_at" IS NULL AND
+			"user_id" = $1
+	`, userId)
+	if err != nil {
+		return "", fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE "refresh_tokens"
+		SET
+			"revoked_at" = NOW()
+		WHERE
+			"revoked_at" IS NULL AND
+			"user_id" = $1
+	`, userId)
+	if err != nil {
+		return "", fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return userId, nil
+}
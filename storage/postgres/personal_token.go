@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"auth/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type personalTokenRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPersonalTokenRepo(db *pgxpool.Pool) *personalTokenRepo {
+	return &personalTokenRepo{
+		db: db,
+	}
+}
+
+func (b *personalTokenRepo) CreatePersonalToken(c context.Context, req *models.CreatePersonalToken) (string, error) {
+	id := uuid.NewString()
+
+	query := `
+		INSERT INTO "personal_access_tokens"(
+			"id",
+			"user_id",
+			"name",
+			"token_hash",
+			"scopes",
+			"expires_at",
+			"created_at")
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`
+	_, err := b.db.Exec(c, query,
+		id,
+		req.UserId,
+		req.Name,
+		req.TokenHash,
+		req.Scopes,
+		req.ExpiresAt,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create personal access token: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetPersonalTokenByHash returns the token with its owner. Tokens of deleted users are not found.
+func (b *personalTokenRepo) GetPersonalTokenByHash(c context.Context, hash string) (*models.PersonalTokenAuth, error) {
+	var (
+		expires_at sql.NullTime
+		revoked_at sql.NullTime
+	)
+
+	query := `
+		SELECT
+			t."id",
+			t."user_id",
+			u."username",
+			u."role",
+			u."email_verified_at" IS NOT NULL,
+			u."phone_verified_at" IS NOT NULL,
+			t."scopes",
+			t."expires_at",
+			t."revoked_at"
+		FROM "personal_access_tokens" t
+		JOIN "users" u ON u."id" = t."user_id"
+		WHERE
+			u."is_active" = true AND
+			t."token_hash" = $1
+	`
+
+	token := models.PersonalTokenAuth{}
+	err := b.db.QueryRow(c, query, hash).Scan(
+		&token.ID,
+		&token.UserId,
+		&token.Username,
+		&token.Role,
+		&token.EmailVerified,
+		&token.PhoneVerified,
+		&token.Scopes,
+		&expires_at,
+		&revoked_at,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("personal access token not found")
+		}
+		return nil, fmt.Errorf("failed to get personal access token: %w", err)
+	}
+	token.ExpiresAt = expires_at.Time
+	token.Revoked = revoked_at.Valid
+
+	return &token, nil
+}
+
+// TouchPersonalToken updates the last used time, at most once a minute
+func (b *personalTokenRepo) TouchPersonalToken(c context.Context, id string) error {
+	query := `
+		UPDATE "personal_access_tokens"
+		SET
+			"last_used_at" = NOW()
+		WHERE
+			"id" = $1 AND
+			("last_used_at" IS NULL OR "last_used_at" < NOW() - INTERVAL '1 minute')
+	`
+
+	_, err := b.db.Exec(c, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to update personal access token: %w", err)
+	}
+
+	return nil
+}
+
+func (b *personalTokenRepo) GetMyPersonalTokens(c context.Context, userId string) (*models.GetAllPersonalTokens, error) {
+	query := `
+		SELECT
+			"id",
+			"name",
+			"scopes",
+			"expires_at",
+			"last_used_at",
+			"created_at"
+		FROM "personal_access_tokens"
+		WHERE
+			"revoked_at" IS NULL AND
+			"user_id" = $1
+		ORDER BY "created_at" DESC
+	`
+
+	rows, err := b.db.Query(c, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]models.PersonalToken, 0)
+	for rows.Next() {
+		var (
+			expires_at   sql.NullTime
+			last_used_at sql.NullTime
+			created_at   sql.NullTime
+		)
+
+		token := models.PersonalToken{}
+		err := rows.Scan(
+			&token.ID,
+			&token.Name,
+			&token.Scopes,
+			&expires_at,
+			&last_used_at,
+			&created_at,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		token.CreatedAt = created_at.Time.Format(time.RFC3339)
+		if expires_at.Valid {
+			token.ExpiresAt = expires_at.Time.Format(time.RFC3339)
+		}
+		if last_used_at.Valid {
+			token.LastUsedAt = last_used_at.Time.Format(time.RFC3339)
+		}
+
+		tokens = append(tokens, token)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.GetAllPersonalTokens{
+		Tokens: tokens,
+		Count:  len(tokens),
+	}, nil
+}
+
+func (b *personalTokenRepo) RevokePersonalToken(c context.Context, req *models.RevokePersonalToken) (string, error) {
+	query := `
+		UPDATE "personal_access_tokens"
+		SET
+			"revoked_at" = NOW()
+		WHERE
+			"revoked_at" IS NULL AND
+			"user_id" = $1 AND
+			"id" = $2
+	`
+
+	result, err := b.db.Exec(c, query, req.UserId, req.Id)
+	if err != nil {
+		return "", fmt.Errorf("failed to revoke personal access token: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return "", fmt.Errorf("personal access token not found")
+	}
+
+	return req.Id, nil
+}
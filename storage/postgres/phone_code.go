@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"auth/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type phoneCodeRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPhoneCodeRepo(db *pgxpool.Pool) *phoneCodeRepo {
+	return &phoneCodeRepo{
+		db: db,
+	}
+}
+
+// LastPhoneCodeAt returns when the last code was sent to the phone, or zero time
+func (b *phoneCodeRepo) LastPhoneCodeAt(c context.Context, phone, purpose string) (time.Time, error) {
+	query := `
+		SELECT MAX("created_at")
+		FROM "phone_codes"
+		WHERE
+			"phone" = $1 AND
+			"purpose" = $2
+	`
+
+	var created_at sql.NullTime
+	err := b.db.QueryRow(c, query, phone, purpose).Scan(&created_at)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get last phone code: %w", err)
+	}
+
+	return created_at.Time, nil
+}
+
+// CreatePhoneCode stores a new code and invalidates the ones sent before
+func (b *phoneCodeRepo) CreatePhoneCode(c context.Context, req *models.CreatePhoneCode) error {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	_, err = tx.Exec(c, `
+		UPDATE "phone_codes"
+		SET
+			"used_at" = NOW()
+		WHERE
+			"used_at" IS NULL AND
+			"phone" = $1 AND
+			"purpose" = $2
+	`, req.Phone, req.Purpose)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate phone codes: %w", err)
+	}
+
+	_, err = tx.Exec(c, `
+		INSERT INTO "phone_codes"(
+			"id",
+			"phone",
+			"purpose",
+			"code_hash",
+			"expires_at",
+			"created_at")
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`, uuid.NewString(), req.Phone, req.Purpose, req.CodeHash, req.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create phone code: %w", err)
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UsePhoneCode checks the code against the latest active code of the phone.
+// Every check counts as an attempt, a code stops working after MaxAttempts.
+func (b *phoneCodeRepo) UsePhoneCode(c context.Context, req *models.UsePhoneCode) (bool, error) {
+	query := `
+		WITH latest AS (
+			SELECT "id"
+			FROM "phone_codes"
+			WHERE
+				"used_at" IS NULL AND
+				"expires_at" > NOW() AND
+				"attempts" < $4 AND
+				"phone" = $1 AND
+				"purpose" = $2
+			ORDER BY "created_at" DESC
+			LIMIT 1
+			FOR UPDATE
+		)
+		UPDATE "phone_codes"
+		SET
+			"attempts" = "attempts" + 1,
+			"used_at" = CASE WHEN "code_hash" = $3 THEN NOW() END
+		WHERE "id" IN (SELECT "id" FROM latest)
+		RETURNING "used_at" IS NOT NULL
+	`
+
+	used := false
+	err := b.db.QueryRow(c, query, req.Phone, req.Purpose, req.CodeHash, req.MaxAttempts).Scan(&used)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to use phone code: %w", err)
+	}
+
+	return used, nil
+}
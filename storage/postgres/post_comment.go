@@ -27,6 +27,13 @@ func (r *commentRepo) CreateComment(ctx context.Context, req *models.CreateComme
 	userInfo := ctx.Value("user_info").(helper.TokenInfo)
 	id := uuid.NewString()
 
+	if err := blockedAuthor(ctx, r.db, userInfo.User_id, "post", req.PostId); err != nil {
+		return "", err
+	}
+	if err := hiddenPost(ctx, r.db, userInfo.User_id, "post", req.PostId); err != nil {
+		return "", err
+	}
+
 	query := `
 		INSERT INTO "post_comments" (
 			"id",
@@ -118,10 +125,19 @@ func (b *commentRepo) GetMyComments(c context.Context) (resp *models.GetAllComme
 	return response, nil
 }
 
-// get all post comments
+// get all post comments req.ViewerId may see, none when they can't see the
+// post itself. See hiddenAuthorFilter.
 func (b *commentRepo) GetPostComments(c context.Context, req *models.GetAllPostComments) (*models.GetAllCommentResponse, error) {
+	visible := hiddenAuthorFilter(`post_comments."created_by"`, "$1") + `
+		AND EXISTS (
+			SELECT 1
+			FROM "post" cp
+			WHERE
+				cp."id" = post_comments."post_id" AND
+				cp."deleted_at" IS NULL` + visibleAuthorFilter(`cp."created_by"`, "$1") + `
+		) `
 
-	filter := fmt.Sprintf(`  WHERE "deleted_at" IS NULL AND "post_id" = '%s'`, *req.PostId)
+	filter := `  WHERE "deleted_at" IS NULL AND "post_id" = $2` + visible
 
 	query := `
 		SELECT 
@@ -137,7 +153,7 @@ func (b *commentRepo) GetPostComments(c context.Context, req *models.GetAllPostC
 		FROM "post_comments"
 	`
 
-	countQuery := fmt.Sprintf(`SELECT count(*) FROM "post_comments" WHERE "deleted_at" IS NULL AND "post_id" = '%s'`, *req.PostId)
+	countQuery := `SELECT count(*) FROM "post_comments" WHERE "deleted_at" IS NULL AND "post_id" = $2` + visible
 
 	if *req.Page != 0 && *req.Limit != 0 {
 		offset := (*req.Page - 1) * (*req.Limit)
@@ -146,7 +162,7 @@ func (b *commentRepo) GetPostComments(c context.Context, req *models.GetAllPostC
 
 	query += filter
 
-	rows, err := b.db.Query(c, query)
+	rows, err := b.db.Query(c, query, req.ViewerId, *req.PostId)
 	if err != nil {
 		return nil, err
 	}
@@ -180,7 +196,7 @@ func (b *commentRepo) GetPostComments(c context.Context, req *models.GetAllPostC
 	}
 
 	count := 0
-	err = b.db.QueryRow(c, countQuery).Scan(&count)
+	err = b.db.QueryRow(c, countQuery, req.ViewerId, *req.PostId).Scan(&count)
 	if err != nil {
 		return nil, err
 	}
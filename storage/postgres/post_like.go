@@ -4,8 +4,10 @@ import (
 	"auth/models"
 	"auth/pkg/helper"
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx"
@@ -26,6 +28,13 @@ func (b *likeRepo) AddLike(c context.Context, req *models.CreateLike) error {
 	// Check if the user has already liked the post
 	userInfo := c.Value("user_info").(helper.TokenInfo)
 
+	if err := blockedAuthor(c, b.db, userInfo.User_id, "post", req.PostId); err != nil {
+		return err
+	}
+	if err := hiddenPost(c, b.db, userInfo.User_id, "post", req.PostId); err != nil {
+		return err
+	}
+
 	query := `
 		SELECT COUNT(id) FROM post_likes WHERE post_id = $1 AND user_id = $2
 	`
@@ -103,3 +112,44 @@ func (b *likeRepo) GetLikesCount(c context.Context, req string) (int, error) {
 
 	return count, nil
 }
+
+// GetUserLikes returns every like the user made, removed ones included
+func (b *likeRepo) GetUserLikes(c context.Context, userId string) ([]models.Like, error) {
+	query := `
+		SELECT
+			"id",
+			"post_id",
+			"created_at",
+			"deleted_at"
+		FROM "post_likes"
+		WHERE "user_id" = $1
+		ORDER BY "created_at" DESC
+	`
+
+	rows, err := b.db.Query(c, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get likes: %w", err)
+	}
+	defer rows.Close()
+
+	likes := make([]models.Like, 0)
+	for rows.Next() {
+		var (
+			created_at time.Time
+			deleted_at sql.NullTime
+		)
+
+		like := models.Like{UserId: userId}
+		if err := rows.Scan(&like.ID, &like.PostId, &created_at, &deleted_at); err != nil {
+			return nil, err
+		}
+		like.CreatedAt = created_at.Format(time.RFC3339)
+		if deleted_at.Valid {
+			like.DeletedAt = deleted_at.Time.Format(time.RFC3339)
+		}
+
+		likes = append(likes, like)
+	}
+
+	return likes, rows.Err()
+}
@@ -10,12 +10,28 @@ import (
 )
 
 type store struct {
-	db           *pgxpool.Pool
-	users        *userRepo
-	posts        *postRepo
-	likes        *likeRepo
-	comments     *commentRepo
-	commentLikes *commentLikeRepo
+	db                 *pgxpool.Pool
+	users              *userRepo
+	posts              *postRepo
+	likes              *likeRepo
+	comments           *commentRepo
+	commentLikes       *commentLikeRepo
+	refreshTokens      *refreshTokenRepo
+	passwordResets     *passwordResetRepo
+	emailVerifications *emailVerificationRepo
+	loginFailures      *loginFailureRepo
+	twoFactor          *twoFactorRepo
+	sessions           *sessionRepo
+	personalTokens     *personalTokenRepo
+	identities         *identityRepo
+	magicLinks         *magicLinkRepo
+	phoneCodes         *phoneCodeRepo
+	verifications      *verificationRepo
+	authEvents         *authEventRepo
+	passkeys           *passkeyRepo
+	follows            *followRepo
+	blocks             *blockRepo
+	exports            *exportRepo
 }
 
 func NewStorage(ctx context.Context, cfg config.Config) (storage.StorageI, error) {
@@ -77,3 +93,115 @@ func (b *store) CommentLike() storage.CommentLikeI {
 	}
 	return b.commentLikes
 }
+
+func (b *store) RefreshToken() storage.RefreshTokensI {
+	if b.refreshTokens == nil {
+		b.refreshTokens = NewRefreshTokenRepo(b.db)
+	}
+	return b.refreshTokens
+}
+
+func (b *store) PasswordReset() storage.PasswordResetsI {
+	if b.passwordResets == nil {
+		b.passwordResets = NewPasswordResetRepo(b.db)
+	}
+	return b.passwordResets
+}
+
+func (b *store) EmailVerification() storage.EmailVerificationsI {
+	if b.emailVerifications == nil {
+		b.emailVerifications = NewEmailVerificationRepo(b.db)
+	}
+	return b.emailVerifications
+}
+
+func (b *store) LoginFailure() storage.LoginFailuresI {
+	if b.loginFailures == nil {
+		b.loginFailures = NewLoginFailureRepo(b.db)
+	}
+	return b.loginFailures
+}
+
+func (b *store) TwoFactor() storage.TwoFactorI {
+	if b.twoFactor == nil {
+		b.twoFactor = NewTwoFactorRepo(b.db)
+	}
+	return b.twoFactor
+}
+
+func (b *store) Session() storage.SessionsI {
+	if b.sessions == nil {
+		b.sessions = NewSessionRepo(b.db)
+	}
+	return b.sessions
+}
+
+func (b *store) PersonalToken() storage.PersonalTokensI {
+	if b.personalTokens == nil {
+		b.personalTokens = NewPersonalTokenRepo(b.db)
+	}
+	return b.personalTokens
+}
+
+func (b *store) Identity() storage.IdentitiesI {
+	if b.identities == nil {
+		b.identities = NewIdentityRepo(b.db)
+	}
+	return b.identities
+}
+
+func (b *store) MagicLink() storage.MagicLinksI {
+	if b.magicLinks == nil {
+		b.magicLinks = NewMagicLinkRepo(b.db)
+	}
+	return b.magicLinks
+}
+
+func (b *store) PhoneCode() storage.PhoneCodesI {
+	if b.phoneCodes == nil {
+		b.phoneCodes = NewPhoneCodeRepo(b.db)
+	}
+	return b.phoneCodes
+}
+
+func (b *store) Verification() storage.VerificationsI {
+	if b.verifications == nil {
+		b.verifications = NewVerificationRepo(b.db)
+	}
+	return b.verifications
+}
+
+func (b *store) AuthEvent() storage.AuthEventsI {
+	if b.authEvents == nil {
+		b.authEvents = NewAuthEventRepo(b.db)
+	}
+	return b.authEvents
+}
+
+func (b *store) Passkey() storage.PasskeysI {
+	if b.passkeys == nil {
+		b.passkeys = NewPasskeyRepo(b.db)
+	}
+	return b.passkeys
+}
+
+func (b *store) Follow() storage.FollowsI {
+	if b.follows == nil {
+		b.follows = NewFollowRepo(b.db)
+	}
+	return b.follows
+}
+
+func (b *store) Block() storage.BlocksI {
+	if b.blocks == nil {
+		b.blocks = NewBlockRepo(b.db)
+	}
+	return b.blocks
+}
+
+func (b *store) Export() storage.ExportsI {
+	if b.exports == nil {
+		b.exports = NewExportRepo(b.db)
+	}
+	return b.exports
+}
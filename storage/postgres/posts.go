@@ -3,6 +3,7 @@ package postgres
 import (
 	"auth/models"
 	"auth/pkg/helper"
+	"auth/storage"
 	"context"
 	"database/sql"
 	"fmt"
@@ -51,9 +52,15 @@ func (b *postRepo) CreatePost(c context.Context, req *models.CreatePost) (string
 	return id, nil
 }
 
+// GetPost returns a post the user in the context may see, see visibleAuthorFilter
 func (b *postRepo) GetPost(c context.Context, req *models.IdRequest) (resp *models.Post, err error) {
 	var created_at sql.NullString
 
+	viewerId := ""
+	if userInfo, ok := c.Value("user_info").(helper.TokenInfo); ok {
+		viewerId = userInfo.User_id
+	}
+
 	query := `
 		SELECT
 			p."id",
@@ -64,24 +71,31 @@ func (b *postRepo) GetPost(c context.Context, req *models.IdRequest) (resp *mode
 				FROM "post_likes"
 				WHERE "deleted_at" IS NULL
 				AND "post_id" = p."id"
-			) AS "likes_count"
+			) AS "likes_count",
+			u."id",
+			u."username",
+			u."is_verified"
 		FROM "post" p
+		JOIN "users" u ON u."id" = p."created_by"
 		WHERE
 			p."deleted_at" IS NULL
 			AND p."id" = $1
-	`
+	` + visibleAuthorFilter(`p."created_by"`, "$2")
 
 	post := models.Post{}
-	err = b.db.QueryRow(c, query, req.Id).Scan(
+	err = b.db.QueryRow(c, query, req.Id, viewerId).Scan(
 		&post.ID,
 		&post.Description,
 		&post.Photos,
 		&created_at,
 		&post.LikeCount,
+		&post.Author.ID,
+		&post.Author.Username,
+		&post.Author.IsVerified,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("post not found")
+			return nil, storage.ErrPostNotFound
 		}
 		return nil, fmt.Errorf("failed to get post: %w", err)
 	}
@@ -91,38 +105,46 @@ func (b *postRepo) GetPost(c context.Context, req *models.IdRequest) (resp *mode
 	return &post, nil
 }
 
+// GetAllActivePost lists the active posts req.ViewerId may see, leaving out
+// the ones of users they muted. See hiddenAuthorFilter.
 func (b *postRepo) GetAllActivePost(c context.Context, req *models.GetAllPostRequest) (*models.GetAllPost, error) {
-	filter := ` WHERE deleted_at IS NULL `
+	filter := ` WHERE p.deleted_at IS NULL ` + hiddenAuthorFilter(`p."created_by"`, "$1")
 
 	query := `
 		SELECT 
-			"id", 
-			"description", 
-			"photos", 
+			p."id", 
+			p."description", 
+			p."photos", 
 			(SELECT COUNT(*) 
 				FROM "post_likes"
 				WHERE "deleted_at" IS NULL
 				AND "post_id" = p."id"
 			) AS "likes_count",
-			"created_at"
+			p."created_at",
+			u."id",
+			u."username",
+			u."is_verified"
 		FROM "post" p
+		JOIN "users" u ON u."id" = p."created_by"
 	`
 
-	countQuery := `SELECT count(*) FROM post WHERE deleted_at IS NULL `
+	countQuery := `SELECT count(*) FROM post p WHERE p.deleted_at IS NULL ` + hiddenAuthorFilter(`p."created_by"`, "$1")
 
+	args := []interface{}{req.ViewerId}
 	if *req.Search != "" {
-		filter += fmt.Sprintf(` AND description ILIKE  '%s' `, "%"+*req.Search+"%")
-		countQuery += fmt.Sprintf(` AND description ILIKE '%s'`, *req.Search)
+		args = append(args, "%"+*req.Search+"%")
+		filter += ` AND p.description ILIKE $2 `
+		countQuery += ` AND p.description ILIKE $2`
 	}
 
 	if *req.Page != 0 && *req.Limit != 0 {
 		offset := (*req.Page - 1) * (*req.Limit)
-		filter += fmt.Sprintf(" ORDER BY created_at desc LIMIT %d OFFSET %d", *req.Limit, offset)
+		filter += fmt.Sprintf(" ORDER BY p.created_at desc LIMIT %d OFFSET %d", *req.Limit, offset)
 	}
 
 	query += filter
 
-	rows, err := b.db.Query(c, query)
+	rows, err := b.db.Query(c, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -142,6 +164,9 @@ func (b *postRepo) GetAllActivePost(c context.Context, req *models.GetAllPostReq
 			&post.Photos,
 			&post.LikeCount,
 			&created_at,
+			&post.Author.ID,
+			&post.Author.Username,
+			&post.Author.IsVerified,
 		)
 		if err != nil {
 			return nil, err
@@ -157,7 +182,7 @@ func (b *postRepo) GetAllActivePost(c context.Context, req *models.GetAllPostReq
 	}
 
 	count := 0
-	err = b.db.QueryRow(c, countQuery).Scan(&count)
+	err = b.db.QueryRow(c, countQuery, args...).Scan(&count)
 	if err != nil {
 		return nil, err
 	}
@@ -173,32 +198,36 @@ func (b *postRepo) GetAllActivePost(c context.Context, req *models.GetAllPostReq
 func (b *postRepo) GetAllMyActivePost(c context.Context, req *models.GetAllMyPostRequest) (*models.GetAllPost, error) {
 	userInfo := c.Value("user_info").(helper.TokenInfo)
 
-	filter := fmt.Sprintf(` WHERE deleted_at IS NULL AND created_by = '%s'`, userInfo.User_id)
+	filter := fmt.Sprintf(` WHERE p.deleted_at IS NULL AND p.created_by = '%s'`, userInfo.User_id)
 
 	query := `
 		SELECT 
-			"id", 
-			"description", 
-			"photos", 
+			p."id", 
+			p."description", 
+			p."photos", 
 			(SELECT COUNT(*) 
 				FROM "post_likes"
 				WHERE "deleted_at" IS NULL
-				AND "post_id" = post."id"
+				AND "post_id" = p."id"
 			) AS "likes_count",
-			"created_at"
-		FROM "post" 
+			p."created_at",
+			u."id",
+			u."username",
+			u."is_verified"
+		FROM "post" p
+		JOIN "users" u ON u."id" = p."created_by"
 	`
 
 	countQuery := fmt.Sprintf(`SELECT count(*) FROM post WHERE deleted_at IS NULL AND created_by = '%s'`, userInfo.User_id)
 
 	if *req.Search != "" {
-		filter += fmt.Sprintf(` AND description ILIKE  '%s' `, "%"+*req.Search+"%")
+		filter += fmt.Sprintf(` AND p.description ILIKE  '%s' `, "%"+*req.Search+"%")
 		countQuery += fmt.Sprintf(` AND description ILIKE '%s'`, "%"+*req.Search+"%")
 	}
 
 	if *req.Page != 0 && *req.Limit != 0 {
 		offset := (*req.Page - 1) * (*req.Limit)
-		filter += fmt.Sprintf(" ORDER BY created_at desc LIMIT %d OFFSET %d", *req.Limit, offset)
+		filter += fmt.Sprintf(" ORDER BY p.created_at desc LIMIT %d OFFSET %d", *req.Limit, offset)
 	}
 
 	query += filter
@@ -222,6 +251,9 @@ func (b *postRepo) GetAllMyActivePost(c context.Context, req *models.GetAllMyPos
 			&post.Photos,
 			&post.LikeCount,
 			&created_at,
+			&post.Author.ID,
+			&post.Author.Username,
+			&post.Author.IsVerified,
 		)
 		if err != nil {
 			return nil, err
@@ -316,32 +348,36 @@ func (b *postRepo) DeletePost(c context.Context, req *models.DeletePost) (resp s
 }
 
 func (b *postRepo) GetAllDeletedPost(c context.Context, req *models.GetAllPostRequest) (*models.GetAllPost, error) {
-	filter := ` WHERE deleted_at IS NOT NULL`
+	filter := ` WHERE p.deleted_at IS NOT NULL`
 
 	query := `
 		SELECT 
-			"id", 
-			"description", 
-			"photos", 
+			p."id", 
+			p."description", 
+			p."photos", 
 			(SELECT COUNT(*) 
 				FROM "post_likes"
 				WHERE "deleted_at" IS NOT NULL
-				AND "post_id" = post."id"
+				AND "post_id" = p."id"
 			) AS "likes_count",
-			"created_at"
-		FROM "post" 
+			p."created_at",
+			u."id",
+			u."username",
+			u."is_verified"
+		FROM "post" p
+		JOIN "users" u ON u."id" = p."created_by"
 	`
 
 	countQuery := `SELECT count(*) FROM post WHERE deleted_at IS NOT NULL `
 
 	if *req.Search != "" {
-		filter += fmt.Sprintf(` AND description ILIKE  '%s' `, "%"+*req.Search+"%")
+		filter += fmt.Sprintf(` AND p.description ILIKE  '%s' `, "%"+*req.Search+"%")
 		countQuery += fmt.Sprintf(` AND description ILIKE '%s'`, *req.Search)
 	}
 
 	if *req.Page != 0 && *req.Limit != 0 {
 		offset := (*req.Page - 1) * (*req.Limit)
-		filter += fmt.Sprintf(" ORDER BY created_at desc LIMIT %d OFFSET %d", *req.Limit, offset)
+		filter += fmt.Sprintf(" ORDER BY p.created_at desc LIMIT %d OFFSET %d", *req.Limit, offset)
 	}
 
 	query += filter
@@ -364,7 +400,11 @@ func (b *postRepo) GetAllDeletedPost(c context.Context, req *models.GetAllPostRe
 			&post.ID,
 			&post.Description,
 			&post.Photos,
+			&post.LikeCount,
 			&created_at,
+			&post.Author.ID,
+			&post.Author.Username,
+			&post.Author.IsVerified,
 		)
 		if err != nil {
 			return nil, err
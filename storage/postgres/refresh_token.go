@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"auth/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type refreshTokenRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewRefreshTokenRepo(db *pgxpool.Pool) *refreshTokenRepo {
+	return &refreshTokenRepo{
+		db: db,
+	}
+}
+
+func (b *refreshTokenRepo) CreateRefreshToken(c context.Context, req *models.CreateRefreshToken) (string, error) {
+	id := uuid.NewString()
+
+	query := `
+		INSERT INTO "refresh_tokens"(
+			"id",
+			"family_id",
+			"user_id",
+			"token_hash",
+			"expires_at",
+			"created_at")
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`
+	_, err := b.db.Exec(c, query,
+		id,
+		req.FamilyId,
+		req.UserId,
+		req.TokenHash,
+		req.ExpiresAt,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return id, nil
+}
+
+func (b *refreshTokenRepo) GetByHash(c context.Context, hash string) (*models.RefreshToken, error) {
+	var (
+		used_at    sql.NullTime
+		revoked_at sql.NullTime
+	)
+
+	query := `
+		SELECT
+			rt."id",
+			rt."family_id",
+			rt."user_id",
+			u."username",
+			u."role",
+			u."email_verified_at" IS NOT NULL,
+			u."phone_verified_at" IS NOT NULL,
+			rt."expires_at",
+			rt."used_at",
+			rt."revoked_at"
+		FROM "refresh_tokens" rt
+		JOIN "users" u ON u."id" = rt."user_id"
+		WHERE
+			rt."token_hash" = $1 AND ` + canLogInFilter("u.")
+
+	token := models.RefreshToken{}
+	err := b.db.QueryRow(c, query, hash).Scan(
+		&token.ID,
+		&token.FamilyId,
+		&token.UserId,
+		&token.Username,
+		&token.Role,
+		&token.EmailVerified,
+		&token.PhoneVerified,
+		&token.ExpiresAt,
+		&used_at,
+		&revoked_at,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("refresh token not found")
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	token.Used = used_at.Valid
+	token.Revoked = revoked_at.Valid
+
+	return &token, nil
+}
+
+// RotateRefreshToken marks the old token as used and stores its replacement
+// in the same family, whose session then expires with the new token. It fails
+// if the old token was already used, which is how concurrent reuse of a
+// single refresh token is detected.
+func (b *refreshTokenRepo) RotateRefreshToken(c context.Context, oldId string, req *models.CreateRefreshToken) (string, error) {
+	id := uuid.NewString()
+
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	result, err := tx.Exec(c, `
+		UPDATE "refresh_tokens"
+		SET
+			"used_at" = NOW(),
+			"replaced_by" = $1
+		WHERE
+			"used_at" IS NULL AND
+			"revoked_at" IS NULL AND
+			"id" = $2
+	`, id, oldId)
+	if err != nil {
+		return "", fmt.Errorf("failed to mark refresh token as used: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return "", fmt.Errorf("refresh token already used")
+	}
+
+	_, err = tx.Exec(c, `
+		INSERT INTO "refresh_tokens"(
+			"id",
+			"family_id",
+			"user_id",
+			"token_hash",
+			"expires_at",
+			"created_at")
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`, id, req.FamilyId, req.UserId, req.TokenHash, req.ExpiresAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE "sessions"
+		SET
+			"expires_at" = $2
+		WHERE
+			"revoked_at" IS NULL AND
+			"id" = $1
+	`, req.FamilyId, req.ExpiresAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to extend session: %w", err)
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return id, nil
+}
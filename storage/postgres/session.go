@@ -0,0 +1,223 @@
+package postgres
+
+import (
+	"auth/models"
+	"auth/storage"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type sessionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionRepo(db *pgxpool.Pool) *sessionRepo {
+	return &sessionRepo{
+		db: db,
+	}
+}
+
+func (b *sessionRepo) CreateSession(c context.Context, req *models.CreateSession) (string, error) {
+	id := uuid.NewString()
+
+	query := `
+		INSERT INTO "sessions"(
+			"id",
+			"user_id",
+			"user_agent",
+			"ip",
+			"created_at",
+			"last_seen_at",
+			"expires_at")
+		VALUES ($1, $2, LEFT($3, 512), $4, NOW(), NOW(), $5)
+	`
+	_, err := b.db.Exec(c, query,
+		id,
+		req.UserId,
+		req.UserAgent,
+		req.IP,
+		req.ExpiresAt,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return id, nil
+}
+
+// TouchSession reports whether the session is still active, neither revoked
+// nor expired, and refreshes its last seen time, at most once a minute to keep
+// writes low
+func (b *sessionRepo) TouchSession(c context.Context, id string) (bool, error) {
+	query := `
+		WITH active AS (
+			SELECT "id"
+			FROM "sessions"
+			WHERE
+				"revoked_at" IS NULL AND
+				"expires_at" > NOW() AND
+				"id" = $1
+		), touched AS (
+			UPDATE "sessions"
+			SET
+				"last_seen_at" = NOW()
+			WHERE
+				"id" IN (SELECT "id" FROM active) AND
+				"last_seen_at" < NOW() - INTERVAL '1 minute'
+		)
+		SELECT EXISTS (SELECT 1 FROM active)
+	`
+
+	active := false
+	err := b.db.QueryRow(c, query, id).Scan(&active)
+	if err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+
+	return active, nil
+}
+
+func (b *sessionRepo) GetMySessions(c context.Context, userId string) (*models.GetAllSessions, error) {
+	query := `
+		SELECT
+			"id",
+			"user_agent",
+			"ip",
+			"created_at",
+			"last_seen_at",
+			"expires_at"
+		FROM "sessions"
+		WHERE
+			"revoked_at" IS NULL AND
+			"expires_at" > NOW() AND
+			"user_id" = $1
+		ORDER BY "last_seen_at" DESC
+	`
+
+	rows, err := b.db.Query(c, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]models.Session, 0)
+	for rows.Next() {
+		var created_at, last_seen_at, expires_at time.Time
+
+		session := models.Session{}
+		err := rows.Scan(
+			&session.ID,
+			&session.UserAgent,
+			&session.IP,
+			&created_at,
+			&last_seen_at,
+			&expires_at,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		session.CreatedAt = created_at.Format(time.RFC3339)
+		session.LastSeenAt = last_seen_at.Format(time.RFC3339)
+		session.ExpiresAt = expires_at.Format(time.RFC3339)
+
+		sessions = append(sessions, session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.GetAllSessions{
+		Sessions: sessions,
+		Count:    len(sessions),
+	}, nil
+}
+
+// RevokeSession revokes one session of the user together with its refresh tokens
+func (b *sessionRepo) RevokeSession(c context.Context, req *models.RevokeSession) (string, error) {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	result, err := tx.Exec(c, `
+		UPDATE "sessions"
+		SET
+			"revoked_at" = NOW()
+		WHERE
+			"revoked_at" IS NULL AND
+			"user_id" = $1 AND
+			"id" = $2
+	`, req.UserId, req.Id)
+	if err != nil {
+		return "", fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return "", storage.ErrSessionNotFound
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE "refresh_tokens"
+		SET
+			"revoked_at" = NOW()
+		WHERE
+			"revoked_at" IS NULL AND
+			"family_id" = $1
+	`, req.Id)
+	if err != nil {
+		return "", fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return req.Id, nil
+}
+
+// RevokeAllSessions revokes every session of the user except ExceptId, if it is set
+func (b *sessionRepo) RevokeAllSessions(c context.Context, req *models.RevokeAllSessions) (int, error) {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	result, err := tx.Exec(c, `
+		UPDATE "sessions"
+		SET
+			"revoked_at" = NOW()
+		WHERE
+			"revoked_at" IS NULL AND
+			"user_id" = $1 AND
+			"id" != $2
+	`, req.UserId, req.ExceptId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE "refresh_tokens"
+		SET
+			"revoked_at" = NOW()
+		WHERE
+			"revoked_at" IS NULL AND
+			"user_id" = $1 AND
+			"family_id" != $2
+	`, req.UserId, req.ExceptId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"auth/models"
+	"auth/storage"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type twoFactorRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTwoFactorRepo(db *pgxpool.Pool) *twoFactorRepo {
+	return &twoFactorRepo{
+		db: db,
+	}
+}
+
+func (b *twoFactorRepo) GetTwoFactor(c context.Context, userId string) (*models.TwoFactor, error) {
+	query := `
+		SELECT
+			"id",
+			"username",
+			COALESCE("totp_secret", ''),
+			"totp_enabled",
+			"is_active" = false
+		FROM "users"
+		WHERE
+			"id" = $1 AND ` + canLogInFilter("")
+
+	twoFactor := models.TwoFactor{}
+	err := b.db.QueryRow(c, query, userId).Scan(
+		&twoFactor.UserId,
+		&twoFactor.Username,
+		&twoFactor.Secret,
+		&twoFactor.Enabled,
+		&twoFactor.DeletionScheduled,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get two factor settings: %w", err)
+	}
+
+	return &twoFactor, nil
+}
+
+// SetPendingSecret stores a secret that becomes active once it is confirmed.
+// It doesn't touch users that already have two factor enabled.
+func (b *twoFactorRepo) SetPendingSecret(c context.Context, req *models.TwoFactor) error {
+	query := `
+		UPDATE "users"
+		SET
+			"totp_secret" = $1,
+			"totp_last_step" = NULL
+		WHERE
+			"totp_enabled" = false AND
+			"id" = $2
+	`
+
+	result, err := b.db.Exec(c, query, req.Secret, req.UserId)
+	if err != nil {
+		return fmt.Errorf("failed to set totp secret: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("two factor is already enabled")
+	}
+
+	return nil
+}
+
+// EnableTwoFactor turns two factor on and replaces the user's recovery codes
+func (b *twoFactorRepo) EnableTwoFactor(c context.Context, req *models.EnableTwoFactor) error {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	result, err := tx.Exec(c, `
+		UPDATE "users"
+		SET
+			"totp_enabled" = true,
+			"totp_last_step" = $1
+		WHERE
+			"totp_enabled" = false AND
+			"totp_secret" IS NOT NULL AND
+			"id" = $2
+	`, req.Step, req.UserId)
+	if err != nil {
+		return fmt.Errorf("failed to enable two factor: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("two factor is already enabled or not enrolled")
+	}
+
+	_, err = tx.Exec(c, `DELETE FROM "user_recovery_codes" WHERE "user_id" = $1`, req.UserId)
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, hash := range req.CodeHashes {
+		_, err = tx.Exec(c, `
+			INSERT INTO "user_recovery_codes" ("id", "user_id", "code_hash", "created_at")
+			VALUES ($1, $2, $3, NOW())
+		`, uuid.NewString(), req.UserId, hash)
+		if err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (b *twoFactorRepo) DisableTwoFactor(c context.Context, userId string) error {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	_, err = tx.Exec(c, `
+		UPDATE "users"
+		SET
+			"totp_secret" = NULL,
+			"totp_enabled" = false,
+			"totp_last_step" = NULL
+		WHERE "id" = $1
+	`, userId)
+	if err != nil {
+		return fmt.Errorf("failed to disable two factor: %w", err)
+	}
+
+	_, err = tx.Exec(c, `DELETE FROM "user_recovery_codes" WHERE "user_id" = $1`, userId)
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UseTOTPStep records the time step of an accepted code. It returns false when
+// the step (or a later one) was already used, so a code can't be replayed.
+func (b *twoFactorRepo) UseTOTPStep(c context.Context, req *models.UseTOTPStep) (bool, error) {
+	query := `
+		UPDATE "users"
+		SET
+			"totp_last_step" = $1
+		WHERE
+			("totp_last_step" IS NULL OR "totp_last_step" < $1) AND
+			"id" = $2
+	`
+
+	result, err := b.db.Exec(c, query, req.Step, req.UserId)
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp step: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+func (b *twoFactorRepo) UseRecoveryCode(c context.Context, req *models.UseRecoveryCode) (bool, error) {
+	query := `
+		UPDATE "user_recovery_codes"
+		SET
+			"used_at" = NOW()
+		WHERE
+			"used_at" IS NULL AND
+			"user_id" = $1 AND
+			"code_hash" = $2
+	`
+
+	result, err := b.db.Exec(c, query, req.UserId, req.CodeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
@@ -1,15 +1,19 @@
 package postgres
 
 import (
+	"auth/config"
 	"auth/models"
 	"auth/pkg/helper"
+	"auth/storage"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
@@ -23,6 +27,8 @@ func NewUserRepo(db *pgxpool.Pool) *userRepo {
 	}
 }
 
+// CreateUser inserts a user. An account without email has nothing to confirm,
+// so like the accounts predating email sign-up it starts out verified.
 func (b *userRepo) CreateUser(c context.Context, req *models.CreateUser) (string, error) {
 	id := uuid.NewString()
 
@@ -30,16 +36,22 @@ func (b *userRepo) CreateUser(c context.Context, req *models.CreateUser) (string
 		INSERT INTO "users"(
 			"id",
 			"username", 
+			"email",
+			"email_verified_at",
 			"password", 
 			"created_at")
-		VALUES ($1, $2, $3, NOW())
+		VALUES ($1, $2, LOWER(NULLIF($3, '')), CASE WHEN $3 = '' THEN NOW() END, $4, NOW())
 	`
 	_, err := b.db.Exec(context.Background(), query,
 		id,
 		req.Username,
+		req.Email,
 		req.Password,
 	)
 	if err != nil {
+		if taken := uniqueUserViolation(err); taken != nil {
+			return "", taken
+		}
 		return "", fmt.Errorf("failed to create user: %w", err)
 	}
 
@@ -57,7 +69,11 @@ func (b *userRepo) GetUser(c context.Context, req *models.IdRequest) (resp *mode
 			SELECT 
 				"id", 
 				"username", 
-				"password", 
+				COALESCE("email", ''),
+				"email_verified_at" IS NOT NULL,
+				"phone_verified_at" IS NOT NULL,
+				"role",
+				"is_verified",
 				"is_active", 
 				"created_at",
 				"updated_at",
@@ -71,7 +87,11 @@ func (b *userRepo) GetUser(c context.Context, req *models.IdRequest) (resp *mode
 	err = b.db.QueryRow(context.Background(), query, req.Id).Scan(
 		&user.ID,
 		&user.Username,
-		&user.Password,
+		&user.Email,
+		&user.EmailVerified,
+		&user.PhoneVerified,
+		&user.Role,
+		&user.IsVerified,
 		&user.Is_active,
 		&created_at,
 		&updated_at,
@@ -107,7 +127,11 @@ func (b *userRepo) GetAllActiveUser(c context.Context, req *models.GetAllUserReq
 				COUNT(*) OVER(),
 				"id", 
 				"username", 
-				"password", 
+				COALESCE("email", ''),
+				"email_verified_at" IS NOT NULL,
+				"phone_verified_at" IS NOT NULL,
+				"role",
+				"is_verified",
 				"is_active", 
 				"created_at",
 				"updated_at",
@@ -144,7 +168,11 @@ func (b *userRepo) GetAllActiveUser(c context.Context, req *models.GetAllUserReq
 			&resp.Count,
 			&user.ID,
 			&user.Username,
-			&user.Password,
+			&user.Email,
+			&user.EmailVerified,
+			&user.PhoneVerified,
+			&user.Role,
+			&user.IsVerified,
 			&user.Is_active,
 			&created_at,
 			&updated_at,
@@ -167,8 +195,6 @@ func (b *userRepo) GetAllActiveUser(c context.Context, req *models.GetAllUserReq
 }
 
 func (b *userRepo) UpdateUser(c context.Context, req *models.UpdateUser) (string, error) {
-	userInfo := c.Value("user_info").(helper.TokenInfo)
-
 	query := `
 			UPDATE users 
 				SET 
@@ -184,10 +210,13 @@ func (b *userRepo) UpdateUser(c context.Context, req *models.UpdateUser) (string
 		query,
 		req.Username,
 		req.Password,
-		userInfo.User_id,
+		req.ID,
 	)
 
 	if err != nil {
+		if taken := uniqueUserViolation(err); taken != nil {
+			return "", taken
+		}
 		return "", fmt.Errorf("failed to update user: %w", err)
 	}
 
@@ -199,8 +228,6 @@ func (b *userRepo) UpdateUser(c context.Context, req *models.UpdateUser) (string
 }
 
 func (b *userRepo) DeleteUser(c context.Context, req *models.IdRequest) (resp string, err error) {
-	userInfo := c.Value("user_info").(helper.TokenInfo)
-
 	query := `
 	 	UPDATE "users" 
 		SET 
@@ -214,7 +241,7 @@ func (b *userRepo) DeleteUser(c context.Context, req *models.IdRequest) (resp st
 		context.Background(),
 		query,
 		false,
-		userInfo.User_id,
+		req.Id,
 	)
 	if err != nil {
 		return "", err
@@ -240,11 +267,17 @@ func (b *userRepo) GetAllDeletedUser(c context.Context, req *models.GetAllUserRe
 				COUNT(*) OVER(),
 				"id", 
 				"username", 
-				"password", 
+				COALESCE("email", ''),
+				"email_verified_at" IS NOT NULL,
+				"phone_verified_at" IS NOT NULL,
+				"role",
+				"is_verified",
 				"is_active", 
 				"created_at",
 				"updated_at",
-				"deleted_at"
+				"deleted_at",
+				"purge_after",
+				"purged_at"
 			FROM "users"
 		`
 	if req.Search != "" {
@@ -267,9 +300,11 @@ func (b *userRepo) GetAllDeletedUser(c context.Context, req *models.GetAllUserRe
 
 	for rows.Next() {
 		var (
-			created_at sql.NullTime
-			updated_at sql.NullTime
-			deleted_at sql.NullTime
+			created_at  sql.NullTime
+			updated_at  sql.NullTime
+			deleted_at  sql.NullTime
+			purge_after sql.NullTime
+			purged_at   sql.NullTime
 		)
 		user := models.User{}
 
@@ -277,11 +312,17 @@ func (b *userRepo) GetAllDeletedUser(c context.Context, req *models.GetAllUserRe
 			&resp.Count,
 			&user.ID,
 			&user.Username,
-			&user.Password,
+			&user.Email,
+			&user.EmailVerified,
+			&user.PhoneVerified,
+			&user.Role,
+			&user.IsVerified,
 			&user.Is_active,
 			&created_at,
 			&updated_at,
 			&deleted_at,
+			&purge_after,
+			&purged_at,
 		)
 		if err != nil {
 			return nil, err
@@ -293,6 +334,12 @@ func (b *userRepo) GetAllDeletedUser(c context.Context, req *models.GetAllUserRe
 		if deleted_at.Valid {
 			user.DeletedAt = deleted_at.Time.Format(time.RFC3339)
 		}
+		if purge_after.Valid {
+			user.PurgeAfter = purge_after.Time.Format(time.RFC3339)
+		}
+		if purged_at.Valid {
+			user.PurgedAt = purged_at.Time.Format(time.RFC3339)
+		}
 
 		resp.Users = append(resp.Users, user)
 	}
@@ -305,22 +352,666 @@ func (b *userRepo) GetByUsername(c context.Context, req *models.LoginRequest) (r
 			SELECT 
 			"id",
 				"username", 
-				"password"
+				"password",
+				"role",
+				COALESCE("email", ''),
+				"email_verified_at" IS NOT NULL,
+				"phone_verified_at" IS NOT NULL,
+				"totp_enabled",
+				"is_active" = false
 			FROM "users" 
-				WHERE "username"=$1`
+				WHERE LOWER("username") = LOWER($1) AND ` + canLogInFilter("")
 
 	user := models.LoginDataRespond{}
 	err = b.db.QueryRow(context.Background(), query, req.Username).Scan(
 		&user.User_id,
 		&user.Username,
 		&user.Password,
+		&user.Role,
+		&user.Email,
+		&user.EmailVerified,
+		&user.PhoneVerified,
+		&user.TwoFactorEnabled,
+		&user.DeletionScheduled,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, storage.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (b *userRepo) UpdateUserRole(c context.Context, req *models.UpdateUserRole) (string, error) {
+	query := `
+			UPDATE "users"
+				SET
+				"role" = $1,
+				"updated_at" = NOW()
+				WHERE
+				"is_active" = true AND
+				"id" = $2`
+
+	result, err := b.db.Exec(c, query, req.Role, req.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return "", fmt.Errorf("user with ID %s not found", req.ID)
+	}
+
+	return req.ID, nil
+}
+
+func (b *userRepo) GetByEmail(c context.Context, email string) (*models.LoginDataRespond, error) {
+	query := `
+			SELECT
+				"id",
+				"username",
+				"password",
+				"role",
+				"email",
+				"email_verified_at" IS NOT NULL,
+				"phone_verified_at" IS NOT NULL,
+				"totp_enabled",
+				"is_active" = false
+			FROM "users"
+				WHERE
+				LOWER("email") = LOWER($1) AND ` + canLogInFilter("")
+
+	user := models.LoginDataRespond{}
+	err := b.db.QueryRow(c, query, email).Scan(
+		&user.User_id,
+		&user.Username,
+		&user.Password,
+		&user.Role,
+		&user.Email,
+		&user.EmailVerified,
+		&user.PhoneVerified,
+		&user.TwoFactorEnabled,
+		&user.DeletionScheduled,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (b *userRepo) GetPasswordHash(c context.Context, userId string) (string, error) {
+	query := `
+		SELECT "password"
+		FROM "users"
+		WHERE
+			"is_active" = true AND
+			"id" = $1
+	`
+
+	var hash string
+	err := b.db.QueryRow(c, query, userId).Scan(&hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", storage.ErrUserNotFound
+		}
+		return "", fmt.Errorf("failed to get password: %w", err)
+	}
+
+	return hash, nil
+}
+
+func (b *userRepo) ChangePassword(c context.Context, req *models.ChangePassword) error {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	result, err := tx.Exec(c, `
+		UPDATE "users"
+		SET
+			"password" = $1,
+			"updated_at" = NOW()
+		WHERE
+			"is_active" = true AND
+			"id" = $2
+	`, req.Password, req.UserId)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE "sessions"
+		SET
+			"revoked_at" = NOW()
+		WHERE
+			"revoked_at" IS NULL AND
+			"user_id" = $1 AND
+			"id" != $2
+	`, req.UserId, req.KeepSessionId)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE "refresh_tokens"
+		SET
+			"revoked_at" = NOW()
+		WHERE
+			"revoked_at" IS NULL AND
+			"user_id" = $1 AND
+			"family_id" != $2
+	`, req.UserId, req.KeepSessionId)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (b *userRepo) UpgradePasswordHash(c context.Context, req *models.UpgradePasswordHash) error {
+	query := `
+		UPDATE "users"
+		SET
+			"password" = $1
+		WHERE
+			"password" = $2 AND
+			"id" = $3
+	`
+
+	_, err := b.db.Exec(c, query, req.NewHash, req.OldHash, req.UserId)
+	if err != nil {
+		return fmt.Errorf("failed to upgrade password hash: %w", err)
+	}
+
+	return nil
+}
+
+func (b *userRepo) GetByPhone(c context.Context, phone string) (*models.LoginDataRespond, error) {
+	query := `
+		SELECT
+			"id",
+			"username",
+			"role",
+			COALESCE("email", ''),
+			"email_verified_at" IS NOT NULL,
+			"phone_verified_at" IS NOT NULL,
+			"totp_enabled",
+			"is_active" = false
+		FROM "users"
+		WHERE
+			"phone" = $1 AND ` + canLogInFilter("")
+
+	user := models.LoginDataRespond{}
+	err := b.db.QueryRow(c, query, phone).Scan(
+		&user.User_id,
+		&user.Username,
+		&user.Role,
+		&user.Email,
+		&user.EmailVerified,
+		&user.PhoneVerified,
+		&user.TwoFactorEnabled,
+		&user.DeletionScheduled,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	return &user, nil
 }
+
+func (b *userRepo) CreatePhoneUser(c context.Context, req *models.CreatePhoneUser) (string, error) {
+	id := uuid.NewString()
+
+	query := `
+		INSERT INTO "users"(
+			"id",
+			"username",
+			"phone",
+			"phone_verified_at",
+			"password",
+			"created_at")
+		VALUES ($1, $2, $3, NOW(), $4, NOW())
+	`
+	_, err := b.db.Exec(c, query,
+		id,
+		req.Username,
+		req.Phone,
+		req.Password,
+	)
+	if err != nil {
+		if taken := uniqueUserViolation(err); taken != nil {
+			return "", taken
+		}
+		return "", fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return id, nil
+}
+
+// canLogInFilter is an SQL condition keeping the users, in the table with the
+// column prefix, who may log in: active accounts and the ones deleted by their
+// owner that are still in the grace period. A full login restores the latter.
+func canLogInFilter(prefix string) string {
+	return fmt.Sprintf(`(%[1]s"is_active" = true OR (%[1]s"purge_after" > NOW() AND %[1]s"purged_at" IS NULL))`, prefix)
+}
+
+// uniqueUserViolation translates a unique constraint violation on users into
+// the matching storage error, or returns nil
+func uniqueUserViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return nil
+	}
+
+	switch pgErr.ConstraintName {
+	case "users_username_key", "users_username_lower_key":
+		return storage.ErrUsernameTaken
+	case "users_email_key", "users_email_lower_key":
+		return storage.ErrEmailTaken
+	case "users_phone_key":
+		return storage.ErrPhoneTaken
+	}
+	return nil
+}
+
+// GetProfile returns the public profile of an active user by username
+func (b *userRepo) GetProfile(c context.Context, username string) (*models.Profile, error) {
+	var created_at time.Time
+
+	query := `
+		SELECT
+			u."id",
+			u."username",
+			COALESCE(u."display_name", ''),
+			COALESCE(u."bio", ''),
+			COALESCE(u."website", ''),
+			COALESCE(u."location", ''),
+			COALESCE(u."avatar_url", ''),
+			u."is_verified",
+			u."is_private",
+			(
+				SELECT COUNT(*)
+				FROM "post"
+				WHERE "deleted_at" IS NULL AND "created_by" = u."id"
+			),
+			(
+				SELECT COUNT(*)
+				FROM "follows" f
+				JOIN "users" fu ON fu."id" = f."follower_id"
+				WHERE f."deleted_at" IS NULL AND fu."is_active" = true AND f."following_id" = u."id"
+			),
+			(
+				SELECT COUNT(*)
+				FROM "follows" f
+				JOIN "users" fu ON fu."id" = f."following_id"
+				WHERE f."deleted_at" IS NULL AND fu."is_active" = true AND f."follower_id" = u."id"
+			),
+			u."created_at"
+		FROM "users" u
+		WHERE
+			u."is_active" = true AND
+			LOWER(u."username") = LOWER($1)
+	`
+
+	profile := models.Profile{}
+	err := b.db.QueryRow(c, query, username).Scan(
+		&profile.ID,
+		&profile.Username,
+		&profile.DisplayName,
+		&profile.Bio,
+		&profile.Website,
+		&profile.Location,
+		&profile.AvatarURL,
+		&profile.IsVerified,
+		&profile.IsPrivate,
+		&profile.PostsCount,
+		&profile.FollowersCount,
+		&profile.FollowingCount,
+		&created_at,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+	profile.CreatedAt = created_at.Format(time.RFC3339)
+
+	return &profile, nil
+}
+
+// UpdateProfile stores the profile fields and returns the avatar URL the user
+// had before, so a replaced image can be removed
+func (b *userRepo) UpdateProfile(c context.Context, req *models.UpdateProfile) (string, error) {
+	query := `
+		WITH old AS (
+			SELECT "avatar_url"
+			FROM "users"
+			WHERE "id" = $1
+		)
+		UPDATE "users"
+		SET
+			"display_name" = NULLIF($2, ''),
+			"bio" = NULLIF($3, ''),
+			"website" = NULLIF($4, ''),
+			"location" = NULLIF($5, ''),
+			"avatar_url" = CASE WHEN $6 THEN NULLIF($7, '') ELSE "avatar_url" END,
+			"updated_at" = NOW()
+		WHERE
+			"is_active" = true AND
+			"id" = $1
+		RETURNING COALESCE((SELECT "avatar_url" FROM old), '')
+	`
+
+	var oldAvatar string
+	err := b.db.QueryRow(c, query,
+		req.UserId,
+		req.DisplayName,
+		req.Bio,
+		req.Website,
+		req.Location,
+		req.SetAvatar,
+		req.AvatarURL,
+	).Scan(&oldAvatar)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", storage.ErrUserNotFound
+		}
+		return "", fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	return oldAvatar, nil
+}
+
+// UpdatePrivacy makes the account private or public. Making it public
+// approves all the pending follow requests.
+func (b *userRepo) UpdatePrivacy(c context.Context, req *models.UpdatePrivacy) error {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	query := `
+		UPDATE "users"
+		SET
+			"is_private" = $2,
+			"updated_at" = NOW()
+		WHERE
+			"is_active" = true AND
+			"id" = $1
+	`
+	result, err := tx.Exec(c, query, req.UserId, req.IsPrivate)
+	if err != nil {
+		return fmt.Errorf("failed to update privacy: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	if !req.IsPrivate {
+		rows, err := tx.Query(c, `DELETE FROM "follow_requests" WHERE "target_id" = $1 RETURNING "requester_id"`, req.UserId)
+		if err != nil {
+			return fmt.Errorf("failed to delete follow requests: %w", err)
+		}
+		requesters := make([]string, 0)
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			requesters = append(requesters, id)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range requesters {
+			if err = insertFollow(c, tx, id, req.UserId); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ScheduleDeletion deactivates the account right away and marks it to be
+// purged after the grace period
+func (b *userRepo) ScheduleDeletion(c context.Context, req *models.ScheduleDeletion) error {
+	query := `
+		UPDATE "users"
+		SET
+			"is_active" = false,
+			"deleted_at" = NOW(),
+			"purge_after" = $2
+		WHERE
+			"is_active" = true AND
+			"id" = $1
+	`
+
+	result, err := b.db.Exec(c, query, req.UserId, req.PurgeAfter)
+	if err != nil {
+		return fmt.Errorf("failed to schedule deletion: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// RestoreUser reactivates a deleted account that wasn't purged yet
+func (b *userRepo) RestoreUser(c context.Context, userId string) error {
+	query := `
+		UPDATE "users"
+		SET
+			"is_active" = true,
+			"deleted_at" = NULL,
+			"purge_after" = NULL,
+			"updated_at" = NOW()
+		WHERE
+			"is_active" = false AND
+			"purged_at" IS NULL AND
+			"id" = $1
+	`
+
+	result, err := b.db.Exec(c, query, userId)
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetUsersToPurge returns up to limit accounts whose grace period is over
+func (b *userRepo) GetUsersToPurge(c context.Context, limit int) ([]string, error) {
+	query := `
+		SELECT "id"
+		FROM "users"
+		WHERE
+			"is_active" = false AND
+			"purged_at" IS NULL AND
+			"purge_after" <= NOW()
+		ORDER BY "purge_after"
+		LIMIT $1
+	`
+
+	rows, err := b.db.Query(c, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users to purge: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// PurgeUser deletes the content, likes, relations and credentials of an
+// account whose grace period is over and anonymizes the user row. The row is
+// kept because the audit log and the moderation columns of posts point at it.
+// It returns the uploaded files and export archives that should be removed.
+func (b *userRepo) PurgeUser(c context.Context, userId string) (*models.PurgedUser, error) {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	purged := &models.PurgedUser{
+		Media:       make([]string, 0),
+		ExportFiles: make([]string, 0),
+	}
+
+	var avatar sql.NullString
+	err = tx.QueryRow(c, `
+		SELECT "avatar_url"
+		FROM "users"
+		WHERE
+			"is_active" = false AND
+			"purged_at" IS NULL AND
+			"purge_after" <= NOW() AND
+			"id" = $1
+		FOR UPDATE
+	`, userId).Scan(&avatar)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+	if avatar.Valid {
+		purged.Media = append(purged.Media, avatar.String)
+	}
+
+	// comments and likes on the posts go with them
+	rows, err := tx.Query(c, `DELETE FROM "post" WHERE "created_by" = $1 RETURNING COALESCE("photos", '{}')`, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete posts: %w", err)
+	}
+	for rows.Next() {
+		var photos []string
+		if err := rows.Scan(&photos); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		purged.Media = append(purged.Media, photos...)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete posts: %w", err)
+	}
+
+	rows, err = tx.Query(c, `DELETE FROM "data_exports" WHERE "user_id" = $1 RETURNING COALESCE("file_path", '')`, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete exports: %w", err)
+	}
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if path != "" {
+			purged.ExportFiles = append(purged.ExportFiles, path)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete exports: %w", err)
+	}
+
+	queries := []string{
+		`DELETE FROM "post_comments" WHERE "created_by" = $1`,
+		`DELETE FROM "post_likes" WHERE "user_id" = $1`,
+		`DELETE FROM "comment_likes" WHERE "user_id" = $1`,
+		`DELETE FROM "follows" WHERE "follower_id" = $1 OR "following_id" = $1`,
+		`DELETE FROM "follow_requests" WHERE "requester_id" = $1 OR "target_id" = $1`,
+		`DELETE FROM "user_blocks" WHERE "blocker_id" = $1 OR "blocked_id" = $1`,
+		`DELETE FROM "user_mutes" WHERE "muter_id" = $1 OR "muted_id" = $1`,
+		`DELETE FROM "refresh_tokens" WHERE "user_id" = $1`,
+		`DELETE FROM "sessions" WHERE "user_id" = $1`,
+		`DELETE FROM "personal_access_tokens" WHERE "user_id" = $1`,
+		`DELETE FROM "password_reset_tokens" WHERE "user_id" = $1`,
+		`DELETE FROM "email_verification_tokens" WHERE "user_id" = $1`,
+		`DELETE FROM "magic_link_tokens" WHERE "user_id" = $1`,
+		`DELETE FROM "user_recovery_codes" WHERE "user_id" = $1`,
+		`DELETE FROM "user_identities" WHERE "user_id" = $1`,
+		`DELETE FROM "webauthn_credentials" WHERE "user_id" = $1`,
+		`DELETE FROM "webauthn_challenges" WHERE "user_id" = $1`,
+		`DELETE FROM "verification_requests" WHERE "user_id" = $1`,
+	}
+	for _, query := range queries {
+		if _, err = tx.Exec(c, query, userId); err != nil {
+			return nil, fmt.Errorf("failed to purge user data: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(c, `
+		UPDATE "users"
+		SET
+			"username" = 'deleted_' || LEFT(REPLACE("id", '-', ''), 22),
+			"password" = '',
+			"email" = NULL,
+			"email_verified_at" = NULL,
+			"phone" = NULL,
+			"phone_verified_at" = NULL,
+			"totp_secret" = NULL,
+			"totp_enabled" = false,
+			"totp_last_step" = NULL,
+			"is_verified" = false,
+			"display_name" = NULL,
+			"bio" = NULL,
+			"website" = NULL,
+			"location" = NULL,
+			"avatar_url" = NULL,
+			"role" = $2,
+			"purged_at" = NOW()
+		WHERE "id" = $1
+	`, userId, config.RoleUser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to anonymize user: %w", err)
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return purged, nil
+}
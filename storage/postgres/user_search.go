@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"auth/models"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// likeEscaper escapes the LIKE wildcards of a search query, see likePrefix
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePrefix returns a LIKE pattern matching the values starting with query
+func likePrefix(query string) string {
+	return likeEscaper.Replace(query) + "%"
+}
+
+// SearchUsers finds the active users whose username or display name look like
+// req.Query, leaving out the ones blocked by or blocking req.ViewerId. Users
+// are ranked by trigram similarity, and a username or display name starting
+// with the query ranks above any similar one.
+func (b *userRepo) SearchUsers(c context.Context, req *models.SearchUsersRequest) (*models.SearchUsers, error) {
+	query := `
+		SELECT
+			COUNT(*) OVER(),
+			u."id",
+			u."username",
+			COALESCE(u."display_name", ''),
+			COALESCE(u."avatar_url", ''),
+			u."is_verified",
+			u."is_private",
+			EXISTS (
+				SELECT 1
+				FROM "follows" f
+				WHERE
+					f."deleted_at" IS NULL AND
+					f."follower_id" = $3 AND
+					f."following_id" = u."id"
+			)
+		FROM "users" u
+		WHERE
+			u."is_active" = true AND (
+				lower(u."username") % $1 OR
+				$1 <% lower(u."display_name") OR
+				lower(u."username") LIKE $2 OR
+				lower(u."display_name") LIKE $2
+			) ` + notBlockedFilter(`u."id"`, "$3") + `
+		ORDER BY
+			CASE
+				WHEN lower(u."username") LIKE $2 THEN 2
+				WHEN lower(u."display_name") LIKE $2 THEN 1
+				ELSE 0
+			END + GREATEST(
+				similarity(lower(u."username"), $1),
+				word_similarity($1, lower(COALESCE(u."display_name", '')))
+			) DESC,
+			u."is_verified" DESC,
+			u."username"
+		OFFSET $4 LIMIT $5
+	`
+
+	search := strings.ToLower(req.Query)
+	rows, err := b.db.Query(c, query, search, likePrefix(search), req.ViewerId, (req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	resp := &models.SearchUsers{Users: make([]models.UserSearchResult, 0)}
+	for rows.Next() {
+		user := models.UserSearchResult{}
+		err := rows.Scan(
+			&resp.Count,
+			&user.ID,
+			&user.Username,
+			&user.DisplayName,
+			&user.AvatarURL,
+			&user.IsVerified,
+			&user.IsPrivate,
+			&user.IsFollowing,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Users = append(resp.Users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// AutocompleteUsers suggests up to req.Limit active users whose username or
+// display name starts with req.Query, for @-mentions. Username matches come
+// first, then the users req.ViewerId follows. The viewer and the users they
+// blocked or were blocked by are never suggested.
+func (b *userRepo) AutocompleteUsers(c context.Context, req *models.SearchUsersRequest) (*models.GetUserSuggestions, error) {
+	query := `
+		SELECT
+			u."id",
+			u."username",
+			COALESCE(u."display_name", ''),
+			COALESCE(u."avatar_url", ''),
+			u."is_verified"
+		FROM "users" u
+		WHERE
+			u."is_active" = true AND
+			u."id" <> $2 AND (
+				lower(u."username") LIKE $1 OR
+				lower(u."display_name") LIKE $1
+			) ` + notBlockedFilter(`u."id"`, "$2") + `
+		ORDER BY
+			lower(u."username") LIKE $1 DESC,
+			EXISTS (
+				SELECT 1
+				FROM "follows" f
+				WHERE
+					f."deleted_at" IS NULL AND
+					f."follower_id" = $2 AND
+					f."following_id" = u."id"
+			) DESC,
+			u."is_verified" DESC,
+			length(u."username"),
+			u."username"
+		LIMIT $3
+	`
+
+	rows, err := b.db.Query(c, query, likePrefix(strings.ToLower(req.Query)), req.ViewerId, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to autocomplete users: %w", err)
+	}
+	defer rows.Close()
+
+	resp := &models.GetUserSuggestions{Users: make([]models.UserSuggestion, 0)}
+	for rows.Next() {
+		user := models.UserSuggestion{}
+		err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.DisplayName,
+			&user.AvatarURL,
+			&user.IsVerified,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Users = append(resp.Users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,231 @@
+package postgres
+
+import (
+	"auth/models"
+	"auth/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type verificationRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewVerificationRepo(db *pgxpool.Pool) *verificationRepo {
+	return &verificationRepo{
+		db: db,
+	}
+}
+
+// CreateVerificationRequest stores a request for review. Verified users and
+// users with a pending request can't submit another one.
+func (b *verificationRepo) CreateVerificationRequest(c context.Context, req *models.CreateVerificationRequest) (string, error) {
+	id := uuid.NewString()
+
+	query := `
+		INSERT INTO "verification_requests"(
+			"id",
+			"user_id",
+			"pinfl",
+			"passport_number",
+			"status",
+			"created_at")
+		SELECT $1, "id", $3, $4, 'pending', NOW()
+		FROM "users"
+		WHERE
+			"is_active" = true AND
+			"is_verified" = false AND
+			"id" = $2
+	`
+	result, err := b.db.Exec(c, query,
+		id,
+		req.UserId,
+		req.Pinfl,
+		req.PassportNumber,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return "", storage.ErrVerificationPending
+		}
+		return "", fmt.Errorf("failed to create verification request: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return "", storage.ErrAlreadyVerified
+	}
+
+	return id, nil
+}
+
+// GetMyVerificationRequest returns the latest request of the user without the passport data
+func (b *verificationRepo) GetMyVerificationRequest(c context.Context, userId string) (*models.VerificationRequest, error) {
+	var (
+		reject_reason sql.NullString
+		reviewed_at   sql.NullTime
+		created_at    time.Time
+	)
+
+	query := `
+		SELECT
+			"id",
+			"user_id",
+			"status",
+			"reject_reason",
+			"reviewed_at",
+			"created_at"
+		FROM "verification_requests"
+		WHERE "user_id" = $1
+		ORDER BY "created_at" DESC
+		LIMIT 1
+	`
+
+	request := models.VerificationRequest{}
+	err := b.db.QueryRow(c, query, userId).Scan(
+		&request.ID,
+		&request.UserId,
+		&request.Status,
+		&reject_reason,
+		&reviewed_at,
+		&created_at,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrVerificationNotFound
+		}
+		return nil, fmt.Errorf("failed to get verification request: %w", err)
+	}
+
+	request.RejectReason = reject_reason.String
+	request.CreatedAt = created_at.Format(time.RFC3339)
+	if reviewed_at.Valid {
+		request.ReviewedAt = reviewed_at.Time.Format(time.RFC3339)
+	}
+
+	return &request, nil
+}
+
+// GetAllVerificationRequests lists requests for review, oldest first. Passport
+// data is returned encrypted.
+func (b *verificationRepo) GetAllVerificationRequests(c context.Context, req *models.GetAllVerificationRequest) (*models.GetAllVerificationRequests, error) {
+	query := `
+		SELECT
+			COUNT(*) OVER(),
+			r."id",
+			r."user_id",
+			u."username",
+			r."pinfl",
+			r."passport_number",
+			r."status",
+			r."reject_reason",
+			r."reviewed_at",
+			r."created_at"
+		FROM "verification_requests" r
+		JOIN "users" u ON u."id" = r."user_id"
+		WHERE r."status" = $1
+		ORDER BY r."created_at"
+		OFFSET $2 LIMIT $3
+	`
+
+	rows, err := b.db.Query(c, query, req.Status, (req.Page-1)*req.Limit, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	resp := &models.GetAllVerificationRequests{Requests: make([]models.VerificationRequest, 0)}
+	for rows.Next() {
+		var (
+			reject_reason sql.NullString
+			reviewed_at   sql.NullTime
+			created_at    time.Time
+		)
+
+		request := models.VerificationRequest{}
+		err := rows.Scan(
+			&resp.Count,
+			&request.ID,
+			&request.UserId,
+			&request.Username,
+			&request.Pinfl,
+			&request.PassportNumber,
+			&request.Status,
+			&reject_reason,
+			&reviewed_at,
+			&created_at,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		request.RejectReason = reject_reason.String
+		request.CreatedAt = created_at.Format(time.RFC3339)
+		if reviewed_at.Valid {
+			request.ReviewedAt = reviewed_at.Time.Format(time.RFC3339)
+		}
+
+		resp.Requests = append(resp.Requests, request)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ReviewVerificationRequest approves or rejects a pending request. Approving
+// marks the user as verified.
+func (b *verificationRepo) ReviewVerificationRequest(c context.Context, req *models.ReviewVerification) error {
+	tx, err := b.db.Begin(c)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	var userId string
+	err = tx.QueryRow(c, `
+		UPDATE "verification_requests"
+		SET
+			"status" = $1,
+			"reject_reason" = NULLIF($2, ''),
+			"reviewed_by" = $3,
+			"reviewed_at" = NOW()
+		WHERE
+			"status" = 'pending' AND
+			"id" = $4
+		RETURNING "user_id"
+	`, req.Status, req.Reason, req.ReviewerId, req.Id).Scan(&userId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrVerificationNotFound
+		}
+		return fmt.Errorf("failed to review verification request: %w", err)
+	}
+
+	if req.Status == models.VerificationApproved {
+		_, err = tx.Exec(c, `
+			UPDATE "users"
+			SET
+				"is_verified" = true,
+				"updated_at" = NOW()
+			WHERE "id" = $1
+		`, userId)
+		if err != nil {
+			return fmt.Errorf("failed to verify user: %w", err)
+		}
+	}
+
+	if err = tx.Commit(c); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
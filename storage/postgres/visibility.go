@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"auth/storage"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// visibleAuthorFilter is an SQL condition keeping rows whose author column is
+// a user the viewer in placeholder viewer may see: an active user that didn't
+// block the viewer nor was blocked by them, and whose account is public, is
+// the viewer's own or is followed by the viewer. An empty viewer id only sees
+// public accounts.
+func visibleAuthorFilter(author, viewer string) string {
+	return fmt.Sprintf(` AND EXISTS (
+			SELECT 1
+			FROM "users" au
+			WHERE
+				au."id" = %[1]s AND
+				au."is_active" = true AND (
+					au."is_private" = false OR
+					au."id" = %[2]s OR
+					EXISTS (
+						SELECT 1
+						FROM "follows" af
+						WHERE
+							af."deleted_at" IS NULL AND
+							af."follower_id" = %[2]s AND
+							af."following_id" = au."id"
+					)
+				)
+		) `, author, viewer) + notBlockedFilter(author, viewer)
+}
+
+// notBlockedFilter is an SQL condition keeping rows whose user column didn't
+// block the viewer in placeholder viewer nor was blocked by them
+func notBlockedFilter(user, viewer string) string {
+	return fmt.Sprintf(` AND NOT EXISTS (
+			SELECT 1
+			FROM "user_blocks" ub
+			WHERE
+				(ub."blocker_id" = %[2]s AND ub."blocked_id" = %[1]s) OR
+				(ub."blocker_id" = %[1]s AND ub."blocked_id" = %[2]s)
+		) `, user, viewer)
+}
+
+// hiddenAuthorFilter is visibleAuthorFilter that also drops the rows of users
+// the viewer muted, for lists
+func hiddenAuthorFilter(author, viewer string) string {
+	return visibleAuthorFilter(author, viewer) + fmt.Sprintf(` AND NOT EXISTS (
+			SELECT 1
+			FROM "user_mutes" um
+			WHERE um."muter_id" = %[2]s AND um."muted_id" = %[1]s
+		) `, author, viewer)
+}
+
+// hiddenPost returns storage.ErrPostNotFound unless the user may see the post
+// that is, or holds, the row id in table ("post" or "post_comments"), by the
+// same visibleAuthorFilter as the read paths. Likes and comments check it so
+// nobody interacts with a post they couldn't read.
+func hiddenPost(c context.Context, db *pgxpool.Pool, userId, table, id string) error {
+	postId := `t."id"`
+	if table != "post" {
+		postId = `t."post_id"`
+	}
+
+	query := fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM "%s" t
+			JOIN "post" p ON p."id" = %s
+			WHERE
+				t."deleted_at" IS NULL AND
+				p."deleted_at" IS NULL AND
+				t."id" = $2`, table, postId) + visibleAuthorFilter(`p."created_by"`, "$1") + `
+		)
+	`
+
+	visible := false
+	if err := db.QueryRow(c, query, userId, id).Scan(&visible); err != nil {
+		return fmt.Errorf("failed to check post visibility: %w", err)
+	}
+	if !visible {
+		return storage.ErrPostNotFound
+	}
+
+	return nil
+}
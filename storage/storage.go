@@ -3,6 +3,36 @@ package storage
 import (
 	"auth/models"
 	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUsernameTaken = errors.New("username is already used")
+	ErrEmailTaken    = errors.New("email is already used")
+	ErrPhoneTaken    = errors.New("phone is already used")
+
+	ErrAlreadyVerified      = errors.New("user is already verified")
+	ErrVerificationPending  = errors.New("a verification request is already waiting for review")
+	ErrVerificationNotFound = errors.New("verification request not found")
+
+	ErrPasskeyExists   = errors.New("passkey is already registered")
+	ErrPasskeyNotFound = errors.New("passkey not found")
+
+	ErrNotFollowing          = errors.New("user is not followed")
+	ErrFollowRequestNotFound = errors.New("follow request not found")
+
+	ErrPostNotFound = errors.New("post not found")
+
+	ErrSessionNotFound = errors.New("session not found")
+
+	ErrBlocked    = errors.New("you can't interact with this user")
+	ErrNotBlocked = errors.New("user is not blocked")
+	ErrNotMuted   = errors.New("user is not muted")
+
+	ErrExportPending  = errors.New("an export is already being prepared")
+	ErrExportNotFound = errors.New("export not found")
 )
 
 type StorageI interface {
@@ -11,17 +41,49 @@ type StorageI interface {
 	Like() LikesI
 	Comment() PostCommentsI
 	CommentLike() CommentLikeI
+	RefreshToken() RefreshTokensI
+	PasswordReset() PasswordResetsI
+	EmailVerification() EmailVerificationsI
+	LoginFailure() LoginFailuresI
+	TwoFactor() TwoFactorI
+	Session() SessionsI
+	PersonalToken() PersonalTokensI
+	Identity() IdentitiesI
+	MagicLink() MagicLinksI
+	PhoneCode() PhoneCodesI
+	Verification() VerificationsI
+	AuthEvent() AuthEventsI
+	Passkey() PasskeysI
+	Follow() FollowsI
+	Block() BlocksI
+	Export() ExportsI
 }
 
 type UsersI interface {
 	CreateUser(context.Context, *models.CreateUser) (string, error)
 	GetUser(context.Context, *models.IdRequest) (*models.User, error)
 	GetAllActiveUser(context.Context, *models.GetAllUserRequest) (*models.GetAllUser, error)
+	SearchUsers(context.Context, *models.SearchUsersRequest) (*models.SearchUsers, error)
+	AutocompleteUsers(context.Context, *models.SearchUsersRequest) (*models.GetUserSuggestions, error)
 	UpdateUser(context.Context, *models.UpdateUser) (string, error)
 	DeleteUser(context.Context, *models.IdRequest) (string, error)
 
 	GetAllDeletedUser(context.Context, *models.GetAllUserRequest) (*models.GetAllUser, error)
 	GetByUsername(context.Context, *models.LoginRequest) (*models.LoginDataRespond, error)
+	UpdateUserRole(context.Context, *models.UpdateUserRole) (string, error)
+	GetByEmail(context.Context, string) (*models.LoginDataRespond, error)
+	GetPasswordHash(context.Context, string) (string, error)
+	ChangePassword(context.Context, *models.ChangePassword) error
+	UpgradePasswordHash(context.Context, *models.UpgradePasswordHash) error
+	GetByPhone(context.Context, string) (*models.LoginDataRespond, error)
+	CreatePhoneUser(context.Context, *models.CreatePhoneUser) (string, error)
+	GetProfile(context.Context, string) (*models.Profile, error)
+	UpdateProfile(context.Context, *models.UpdateProfile) (string, error)
+	UpdatePrivacy(context.Context, *models.UpdatePrivacy) error
+	ScheduleDeletion(context.Context, *models.ScheduleDeletion) error
+	RestoreUser(context.Context, string) error
+	GetUsersToPurge(context.Context, int) ([]string, error)
+	PurgeUser(context.Context, string) (*models.PurgedUser, error)
 }
 
 type PostsI interface {
@@ -39,6 +101,7 @@ type LikesI interface {
 	AddLike(context.Context, *models.CreateLike) error
 	DeleteLike(context.Context, *models.DeleteLike) (string, error)
 	GetLikesCount(context.Context, string) (int, error)
+	GetUserLikes(context.Context, string) ([]models.Like, error)
 }
 
 type PostCommentsI interface {
@@ -55,4 +118,128 @@ type CommentLikeI interface {
 	AddLike(context.Context, *models.CreateCommentLike) error
 	DeleteLike(context.Context, *models.DeleteCommentLike) (string, error)
 	GetLikesCount(context.Context, string) (int, error)
+	GetUserLikes(context.Context, string) ([]models.CommentLike, error)
+}
+
+type RefreshTokensI interface {
+	CreateRefreshToken(context.Context, *models.CreateRefreshToken) (string, error)
+	GetByHash(context.Context, string) (*models.RefreshToken, error)
+	RotateRefreshToken(context.Context, string, *models.CreateRefreshToken) (string, error)
+}
+
+type PasswordResetsI interface {
+	CountPasswordResets(ctx context.Context, userId string, since time.Time) (int, error)
+	CreatePasswordReset(context.Context, *models.CreatePasswordReset) (string, error)
+	ResetPassword(context.Context, *models.ResetPassword) (string, error)
+}
+
+type EmailVerificationsI interface {
+	CreateEmailVerification(context.Context, *models.CreateEmailVerification) (string, error)
+	VerifyEmail(context.Context, string) (string, error)
+}
+
+type LoginFailuresI interface {
+	GetLoginFailure(context.Context, string) (*models.LoginFailure, error)
+	RegisterLoginFailure(context.Context, *models.RegisterLoginFailure) (*models.LoginFailure, error)
+	ResetLoginFailures(context.Context, string) error
+}
+
+type TwoFactorI interface {
+	GetTwoFactor(context.Context, string) (*models.TwoFactor, error)
+	SetPendingSecret(context.Context, *models.TwoFactor) error
+	EnableTwoFactor(context.Context, *models.EnableTwoFactor) error
+	DisableTwoFactor(context.Context, string) error
+	UseTOTPStep(context.Context, *models.UseTOTPStep) (bool, error)
+	UseRecoveryCode(context.Context, *models.UseRecoveryCode) (bool, error)
+}
+
+type SessionsI interface {
+	CreateSession(context.Context, *models.CreateSession) (string, error)
+	TouchSession(context.Context, string) (bool, error)
+	GetMySessions(context.Context, string) (*models.GetAllSessions, error)
+	RevokeSession(context.Context, *models.RevokeSession) (string, error)
+	RevokeAllSessions(context.Context, *models.RevokeAllSessions) (int, error)
+}
+
+type PersonalTokensI interface {
+	CreatePersonalToken(context.Context, *models.CreatePersonalToken) (string, error)
+	GetPersonalTokenByHash(context.Context, string) (*models.PersonalTokenAuth, error)
+	TouchPersonalToken(context.Context, string) error
+	GetMyPersonalTokens(context.Context, string) (*models.GetAllPersonalTokens, error)
+	RevokePersonalToken(context.Context, *models.RevokePersonalToken) (string, error)
+}
+
+type IdentitiesI interface {
+	CreateOIDCState(context.Context, *models.CreateOIDCState) error
+	UseOIDCState(context.Context, string) (*models.OIDCState, error)
+	GetUserByIdentity(context.Context, string, string) (*models.LoginDataRespond, error)
+	LinkIdentity(context.Context, *models.LinkIdentity) error
+	CreateIdentityUser(context.Context, *models.CreateIdentityUser) (string, error)
+}
+
+type MagicLinksI interface {
+	CountMagicLinks(context.Context, string, time.Time) (int, error)
+	CreateMagicLink(context.Context, *models.CreateMagicLink) error
+	UseMagicLink(context.Context, *models.UseMagicLink) (*models.LoginDataRespond, error)
+}
+
+type PhoneCodesI interface {
+	LastPhoneCodeAt(context.Context, string, string) (time.Time, error)
+	CreatePhoneCode(context.Context, *models.CreatePhoneCode) error
+	UsePhoneCode(context.Context, *models.UsePhoneCode) (bool, error)
+}
+
+type VerificationsI interface {
+	CreateVerificationRequest(context.Context, *models.CreateVerificationRequest) (string, error)
+	GetMyVerificationRequest(context.Context, string) (*models.VerificationRequest, error)
+	GetAllVerificationRequests(context.Context, *models.GetAllVerificationRequest) (*models.GetAllVerificationRequests, error)
+	ReviewVerificationRequest(context.Context, *models.ReviewVerification) error
+}
+
+type AuthEventsI interface {
+	CreateAuthEvent(context.Context, *models.CreateAuthEvent) error
+	GetAuthEvents(context.Context, *models.GetAuthEventsRequest) (*models.GetAllAuthEvents, error)
+}
+
+type PasskeysI interface {
+	CreateWebAuthnChallenge(context.Context, *models.CreateWebAuthnChallenge) error
+	UseWebAuthnChallenge(context.Context, string, string) (string, error)
+	CreatePasskey(context.Context, *models.CreatePasskey) (string, error)
+	GetMyPasskeys(context.Context, string) (*models.GetAllPasskeys, error)
+	GetPasskeyCredentialIds(context.Context, string) ([][]byte, error)
+	GetPasskeyByCredentialId(context.Context, []byte) (*models.PasskeyCredential, error)
+	UsePasskey(context.Context, *models.UsePasskey) (*models.LoginDataRespond, error)
+	DeletePasskey(context.Context, *models.DeletePasskey) (string, error)
+}
+
+type FollowsI interface {
+	Follow(context.Context, *models.Follow) (bool, error)
+	Unfollow(context.Context, *models.Follow) error
+	IsMutual(context.Context, string, string) (bool, error)
+	GetFollowers(context.Context, *models.GetFollowsRequest) (*models.GetAllFollows, error)
+	GetFollowing(context.Context, *models.GetFollowsRequest) (*models.GetAllFollows, error)
+	IsFollowing(context.Context, string, string) (bool, error)
+	RequestFollow(context.Context, *models.Follow) (bool, error)
+	CancelFollowRequest(context.Context, *models.Follow) error
+	GetFollowRequests(context.Context, *models.GetFollowsRequest) (*models.GetAllFollowRequests, error)
+	ApproveFollowRequest(context.Context, *models.ReviewFollowRequest) error
+	RejectFollowRequest(context.Context, *models.ReviewFollowRequest) error
+}
+
+type BlocksI interface {
+	Block(context.Context, *models.Block) error
+	Unblock(context.Context, *models.Block) error
+	IsBlocked(context.Context, string, string) (bool, error)
+	GetBlocks(context.Context, *models.GetBlocksRequest) (*models.GetAllBlocks, error)
+	Mute(context.Context, *models.Mute) error
+	Unmute(context.Context, *models.Mute) error
+	GetMutes(context.Context, *models.GetBlocksRequest) (*models.GetAllBlocks, error)
+}
+
+type ExportsI interface {
+	CreateExport(context.Context, string) (string, error)
+	GetExport(context.Context, *models.GetExport) (*models.DataExport, error)
+	CompleteExport(context.Context, *models.CompleteExport) error
+	FailExport(context.Context, *models.FailExport) error
+	ExpireExports(context.Context, time.Time) ([]string, error)
 }